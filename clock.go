@@ -0,0 +1,16 @@
+package pm5
+
+import "time"
+
+// Clock abstracts the time source used for command pacing
+// This allows the interframe gap to be exercised without real sleeps
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// realClock is the default Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
@@ -0,0 +1,89 @@
+package pm5
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for testing
+// Sleep advances the clock instantly and records the requested duration
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+// newFakeClock creates a fake clock starting at the given time
+func newFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{now: start}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+}
+
+// Advance moves the fake time forward by d
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Slept returns all durations passed to Sleep
+func (c *fakeClock) Slept() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]time.Duration, len(c.slept))
+	copy(result, c.slept)
+	return result
+}
+
+func TestSendCommandEnforcesInterframeGap(t *testing.T) {
+	p, dev, clock := newTestPM5(t)
+	for range 4 {
+		queueResponse(t, dev, statusReady)
+	}
+
+	// The first frame has no predecessor, so it goes out at once
+	mustStatus(t, p)
+	if slept := clock.Slept(); len(slept) != 0 {
+		t.Fatalf("first command slept %v", slept)
+	}
+
+	// Back to back: the whole gap is waited out
+	mustStatus(t, p)
+	// Part of the gap already elapsed: only the remainder is waited
+	clock.Advance(20 * time.Millisecond)
+	mustStatus(t, p)
+	// The gap already elapsed: no wait
+	clock.Advance(100 * time.Millisecond)
+	mustStatus(t, p)
+
+	want := []time.Duration{50 * time.Millisecond, 30 * time.Millisecond}
+	got := clock.Slept()
+	if len(got) != len(want) {
+		t.Fatalf("slept %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sleep %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func mustStatus(t *testing.T, p *PM5) {
+	t.Helper()
+	if _, err := p.GetStatus(); err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package pm5
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/device"
+)
+
+// corpusEntry is one capture in testdata/replay_corpus.json: the frame a getter
+// writes, the PM's response frame, and the decoded value formatted as text
+type corpusEntry struct {
+	Name     string `json:"name"`
+	Getter   string `json:"getter"`
+	Request  string `json:"request"`
+	Response string `json:"response"`
+	Want     string `json:"want"`
+}
+
+// corpusGetters maps corpus getter names to calls formatting their result
+var corpusGetters = map[string]func(p *PM5) (string, error){
+	"GetPower":          uintGetter((*PM5).GetPower),
+	"GetPace":           uintGetter((*PM5).GetPace),
+	"GetHorizontal":     uintGetter((*PM5).GetHorizontal),
+	"GetPMWorkDistance": uintGetter((*PM5).GetPMWorkDistance),
+	"GetBatteryLevel":   uintGetter((*PM5).GetBatteryLevel),
+	"GetStrokeState": func(p *PM5) (string, error) {
+		s, err := p.GetStrokeState()
+		return s.String(), err
+	},
+	"GetVersion": func(p *PM5) (string, error) {
+		v, err := p.GetVersion()
+		if err != nil {
+			return "", err
+		}
+		hw, sw := "-", "-"
+		if v.HasHWVersion {
+			hw = fmt.Sprint(v.HWVersion)
+		}
+		if v.HasSWVersion {
+			sw = fmt.Sprint(v.SWVersion)
+		}
+		return fmt.Sprintf("%s hw=%s sw=%s", v.Model, hw, sw), nil
+	},
+	"GetStrokeStats": func(p *PM5) (string, error) {
+		s, err := p.GetStrokeStats()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("dist=%d drive=%d recovery=%d length=%d count=%d peak=%d impulse=%d avg=%d work=%d",
+			s.StrokeDistance, s.DriveTime, s.RecoveryTime, s.StrokeLength, s.DriveCounter,
+			s.PeakDriveForce, s.ImpulseDriveForce, s.AvgDriveForce, s.WorkPerStroke), nil
+	},
+}
+
+func uintGetter[T uint8 | uint16 | uint32](get func(*PM5) (T, error)) func(*PM5) (string, error) {
+	return func(p *PM5) (string, error) {
+		v, err := get(p)
+		return fmt.Sprint(v), err
+	}
+}
+
+func loadCorpus(t *testing.T) []corpusEntry {
+	t.Helper()
+	data, err := os.ReadFile("testdata/replay_corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []corpusEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestReplayCorpus(t *testing.T) {
+	entries := loadCorpus(t)
+	if len(entries) == 0 {
+		t.Fatal("empty corpus")
+	}
+
+	for _, e := range entries {
+		t.Run(e.Name, func(t *testing.T) {
+			get, ok := corpusGetters[e.Getter]
+			if !ok {
+				t.Fatalf("no harness for getter %q", e.Getter)
+			}
+
+			dev := device.NewReplayDevice()
+			dev.AddCapture(decodeHex(t, e.Request), decodeHex(t, e.Response))
+			p := New(dev)
+			p.SetClock(newFakeClock(time.Now()))
+			if err := p.Connect(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := get(p)
+			if misses := dev.Misses(); len(misses) > 0 {
+				t.Fatalf("%s wrote unexpected frame % X, want %s", e.Getter, misses[0], e.Request)
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", e.Getter, err)
+			}
+			if got != e.Want {
+				t.Errorf("%s = %s, want %s", e.Getter, got, e.Want)
+			}
+		})
+	}
+}
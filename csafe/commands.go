@@ -46,6 +46,13 @@ const (
 	CmdGetCaps       byte = 0x70
 )
 
+// Capability codes for CmdGetCaps
+const (
+	CapCodeProtocol byte = 0x00 // Max rx/tx frame and min interframe gap
+	CapCodePower    byte = 0x01
+	CapCodeText     byte = 0x02
+)
+
 // PM Proprietary CSAFE Command Wrappers
 const (
 	CmdSetPMCfg  byte = 0x76
@@ -238,3 +245,62 @@ const (
 	PMCmdSetSyncDataAll          byte = 0xD8
 	PMCmdSetSyncRowingActiveTime byte = 0xD9
 )
+
+// nonIdempotent lists commands that change PM state relative to its current
+// state, so sending one twice differs from sending it once
+// Everything else either reads data or sets an absolute value.
+var nonIdempotent = map[byte]bool{
+	CmdReset:      true,
+	CmdGoIdle:     true,
+	CmdGoHaveID:   true,
+	CmdGoInUse:    true,
+	CmdGoFinished: true,
+	CmdGoReady:    true,
+	CmdBadID:      true,
+}
+
+// nonIdempotentPM is nonIdempotent for commands inside a PM set wrapper
+var nonIdempotentPM = map[byte]bool{
+	PMCmdSetResetAll:          true,
+	PMCmdSetResetErgNumber:    true,
+	PMCmdSetScreenState:       true, // workout actions: start, terminate, next interval
+	PMCmdConfigureWorkout:     true,
+	PMCmdSetRaceOperationType: true,
+	PMCmdSetRaceStatus:        true,
+	PMCmdSetLogCardMemory:     true,
+}
+
+// IsIdempotent reports whether frame contents can safely be sent again when
+// the response to the first attempt was lost
+// Contents that cannot be walked as [Cmd][ByteCount][Data] are reported as
+// not idempotent.
+func IsIdempotent(contents []byte) bool {
+	return walkIdempotent(contents, nonIdempotent, true)
+}
+
+func walkIdempotent(data []byte, unsafe map[byte]bool, outer bool) bool {
+	for i := 0; i < len(data); {
+		cmd := data[i]
+		i++
+		if unsafe[cmd] {
+			return false
+		}
+		if cmd >= 0x80 {
+			continue
+		}
+		if i >= len(data) {
+			return false
+		}
+		n := int(data[i])
+		i++
+		if i+n > len(data) {
+			return false
+		}
+		if outer && (cmd == CmdSetUserCfg1 || cmd == CmdSetPMCfg || cmd == CmdSetPMData) &&
+			!walkIdempotent(data[i:i+n], nonIdempotentPM, false) {
+			return false
+		}
+		i += n
+	}
+	return true
+}
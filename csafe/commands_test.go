@@ -0,0 +1,28 @@
+package csafe
+
+import "testing"
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []byte
+		want     bool
+	}{
+		{"get status", []byte{CmdGetStatus}, true},
+		{"reset", []byte{CmdReset}, false},
+		{"go ready", []byte{CmdGoReady}, false},
+		{"pm get", []byte{CmdGetPMData, 0x02, PMCmdGetWorkTime, PMCmdGetWorkDistance}, true},
+		{"absolute set", []byte{CmdSetPMCfg, 0x03, PMCmdSetWorkoutType, 0x01, 0x00}, true},
+		{"screen state", []byte{CmdSetPMCfg, 0x04, PMCmdSetScreenState, 0x02, 0x01, 0x01}, false},
+		{"configure workout", []byte{CmdSetPMCfg, 0x03, PMCmdConfigureWorkout, 0x01, 0x01}, false},
+		{"reset all", []byte{CmdSetPMCfg, 0x02, PMCmdSetResetAll, 0x00}, false},
+		{"unsafe after safe", []byte{CmdGetStatus, CmdGoInUse}, false},
+		{"truncated", []byte{CmdSetPMCfg, 0x05, PMCmdSetWorkoutType}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsIdempotent(tt.contents); got != tt.want {
+			t.Errorf("IsIdempotent(%s % X) = %v, want %v", tt.name, tt.contents, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package csafe
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 )
@@ -13,6 +12,8 @@ var (
 	ErrInvalidChecksum  = errors.New("invalid checksum")
 	ErrFrameTooLong     = errors.New("frame exceeds maximum length")
 	ErrInvalidStuffByte = errors.New("invalid byte stuffing value")
+	ErrTruncatedCommand = errors.New("command data truncated")
+	ErrCommandTooLong   = errors.New("command data exceeds 255 bytes")
 )
 
 // Frame represents a CSAFE frame
@@ -37,7 +38,7 @@ type CommandResponse struct {
 	Command     byte
 	ByteCount   byte
 	Data        []byte
-	PMResponses []CommandResponse // Populated when Command is a PM wrapper (0x76, 0x77, 0x7E, 0x7F)
+	PMResponses []CommandResponse // Populated when Command is a PM wrapper (0x1A, 0x76, 0x77, 0x7E, 0x7F)
 }
 
 // FirstPMResponse returns the first PM response, or nil if none
@@ -50,16 +51,26 @@ func (c *CommandResponse) FirstPMResponse() *CommandResponse {
 
 // EncodeFrame encodes a CSAFE frame with byte stuffing
 func EncodeFrame(f *Frame) ([]byte, error) {
-	var buf bytes.Buffer
+	encoded, err := AppendFrame(make([]byte, 0, 2*len(f.Contents)+8), f)
+	if err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
+
+// AppendFrame encodes a CSAFE frame onto the end of dst and returns the extended slice
+// Reusing dst across calls avoids allocating a new buffer for every frame
+func AppendFrame(dst []byte, f *Frame) ([]byte, error) {
+	start := len(dst)
 
 	// Start flag
 	if f.Extended {
-		buf.WriteByte(ExtendedFrameStartFlag)
+		dst = append(dst, ExtendedFrameStartFlag)
 		// Write destination and source addresses (with byte stuffing)
-		stuffAndWrite(&buf, f.Destination)
-		stuffAndWrite(&buf, f.Source)
+		dst = appendStuffed(dst, f.Destination)
+		dst = appendStuffed(dst, f.Source)
 	} else {
-		buf.WriteByte(StandardFrameStartFlag)
+		dst = append(dst, StandardFrameStartFlag)
 	}
 
 	// Calculate checksum on unstuffed contents
@@ -70,26 +81,35 @@ func EncodeFrame(f *Frame) ([]byte, error) {
 
 	// Write contents with byte stuffing
 	for _, b := range f.Contents {
-		stuffAndWrite(&buf, b)
+		dst = appendStuffed(dst, b)
 	}
 
 	// Write checksum with byte stuffing
-	stuffAndWrite(&buf, checksum)
+	dst = appendStuffed(dst, checksum)
 
 	// Stop flag
-	buf.WriteByte(StopFrameFlag)
+	dst = append(dst, StopFrameFlag)
 
-	result := buf.Bytes()
-	if len(result) > MaxFrameLength {
-		return nil, ErrFrameTooLong
+	if len(dst)-start > MaxFrameLength {
+		return dst[:start], ErrFrameTooLong
 	}
 
-	return result, nil
+	return dst, nil
+}
+
+// RoundTrip encodes f and decodes the result, for checking that a frame
+// survives byte stuffing and checksumming unchanged
+func RoundTrip(f *Frame) (*Frame, error) {
+	encoded, err := EncodeFrame(f)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeFrame(encoded)
 }
 
 // DecodeFrame decodes a CSAFE frame with byte unstuffing
 func DecodeFrame(data []byte) (*Frame, error) {
-	if len(data) < 4 { // Minimum: start + content + checksum + stop
+	if len(data) < 3 { // Minimum: start + checksum + stop (contents may be empty)
 		return nil, ErrFrameTooShort
 	}
 
@@ -156,7 +176,19 @@ func DecodeFrame(data []byte) (*Frame, error) {
 }
 
 // ParseResponse parses the contents of a CSAFE response frame
+// A command whose byte count overruns the frame is returned with the bytes
+// that are available; use ParseResponseStrict to reject it instead
 func ParseResponse(contents []byte) (*Response, error) {
+	return parseResponse(contents, false)
+}
+
+// ParseResponseStrict is like ParseResponse but returns an error wrapping
+// ErrTruncatedCommand when a command's declared byte count overruns the frame
+func ParseResponseStrict(contents []byte) (*Response, error) {
+	return parseResponse(contents, true)
+}
+
+func parseResponse(contents []byte, strict bool) (*Response, error) {
 	if len(contents) < 1 {
 		return nil, ErrFrameTooShort
 	}
@@ -166,64 +198,46 @@ func ParseResponse(contents []byte) (*Response, error) {
 		FrameToggle:     (contents[0] & StatusFrameToggleMask) != 0,
 		PrevFrameStatus: contents[0] & StatusPrevFrameStatusMask,
 		StateMachine:    contents[0] & StatusStateMask,
-		CommandData:     make([]CommandResponse, 0),
 	}
 
 	// Parse command responses
-	offset := 1
-	for offset < len(contents) {
-		cmd := contents[offset]
-		offset++
-
-		if offset >= len(contents) {
-			resp.CommandData = append(resp.CommandData, CommandResponse{
-				Command:   cmd,
-				ByteCount: 0,
-				Data:      nil,
-			})
-			break
-		}
-
-		byteCount := contents[offset]
-		offset++
-
-		var data []byte
-		if byteCount > 0 {
-			if offset+int(byteCount) > len(contents) {
-				data = contents[offset:]
-				offset = len(contents)
-			} else {
-				data = contents[offset : offset+int(byteCount)]
-				offset += int(byteCount)
-			}
-		}
-
-		cmdResp := CommandResponse{
-			Command:   cmd,
-			ByteCount: byteCount,
-			Data:      data,
-		}
+	cmds, err := parseCommands(contents[1:], strict)
+	if err != nil {
+		return nil, err
+	}
+	if cmds == nil {
+		cmds = make([]CommandResponse, 0)
+	}
 
+	for i := range cmds {
 		// If this is a PM wrapper command, parse the nested PM responses
-		if isPMWrapper(cmd) && len(data) > 0 {
-			cmdResp.PMResponses = parsePMWrapperData(data)
+		if isPMWrapper(cmds[i].Command) && len(cmds[i].Data) > 0 {
+			cmds[i].PMResponses, err = parseCommands(cmds[i].Data, strict)
+			if err != nil {
+				return nil, err
+			}
 		}
-
-		resp.CommandData = append(resp.CommandData, cmdResp)
 	}
+	resp.CommandData = cmds
 
 	return resp, nil
 }
 
 // isPMWrapper returns true if the command is a PM proprietary wrapper
 func isPMWrapper(cmd byte) bool {
-	return cmd == 0x76 || cmd == 0x77 || cmd == 0x7E || cmd == 0x7F
+	return cmd == CmdSetUserCfg1 || cmd == 0x76 || cmd == 0x77 || cmd == 0x7E || cmd == 0x7F
 }
 
-// parsePMWrapperData parses PM proprietary command responses from wrapper data.
-// PM responses follow the same [Cmd][ByteCount][Data] format as standard CSAFE.
-func parsePMWrapperData(data []byte) []CommandResponse {
+// parseCommands parses a sequence of [Cmd][ByteCount][Data] responses
+// PM wrapper data follows the same format as standard CSAFE responses.
+// In strict mode a missing or overrunning byte count is an error; otherwise
+// the command is kept with whatever data is available.
+func parseCommands(data []byte, strict bool) ([]CommandResponse, error) {
+	// Sizing the slice up front avoids regrowing it on every snapshot poll
 	var responses []CommandResponse
+	if n := countCommands(data); n > 0 {
+		responses = make([]CommandResponse, 0, n)
+	}
 	offset := 0
 
 	for offset < len(data) {
@@ -231,6 +245,9 @@ func parsePMWrapperData(data []byte) []CommandResponse {
 		offset++
 
 		if offset >= len(data) {
+			if strict {
+				return nil, fmt.Errorf("%w: command 0x%02X has no byte count", ErrTruncatedCommand, cmd)
+			}
 			responses = append(responses, CommandResponse{
 				Command:   cmd,
 				ByteCount: 0,
@@ -245,6 +262,10 @@ func parsePMWrapperData(data []byte) []CommandResponse {
 		var cmdData []byte
 		if byteCount > 0 {
 			if offset+int(byteCount) > len(data) {
+				if strict {
+					return nil, fmt.Errorf("%w: command 0x%02X declares %d bytes, %d available",
+						ErrTruncatedCommand, cmd, byteCount, len(data)-offset)
+				}
 				cmdData = data[offset:]
 				offset = len(data)
 			} else {
@@ -260,7 +281,22 @@ func parsePMWrapperData(data []byte) []CommandResponse {
 		})
 	}
 
-	return responses
+	return responses, nil
+}
+
+// countCommands returns how many commands parseCommands will find in data,
+// counting a truncated trailing command as one
+func countCommands(data []byte) int {
+	n := 0
+	for offset := 0; offset < len(data); n++ {
+		offset++
+		if offset >= len(data) {
+			n++
+			break
+		}
+		offset += 1 + int(data[offset])
+	}
+	return n
 }
 
 // BuildCommand builds a single CSAFE command
@@ -277,7 +313,22 @@ func BuildCommand(cmd byte, data ...byte) []byte {
 	return result
 }
 
+// BuildCommandInto appends a single CSAFE command to buf without allocating
+// when buf has spare capacity; the output matches BuildCommand
+func BuildCommandInto(buf []byte, cmd byte, data ...byte) []byte {
+	if cmd&0x80 != 0 && len(data) == 0 {
+		return append(buf, cmd)
+	}
+	buf = append(buf, cmd, byte(len(data)))
+	return append(buf, data...)
+}
+
+// maxCommandData is the largest length expressible in a command's size byte
+const maxCommandData = 0xFF
+
 // BuildPMCommand builds a PM-specific command wrapped in appropriate wrapper
+// The wrapped size is a single byte; use BuildPMCommandChecked when the
+// commands are assembled at runtime and could exceed 255 bytes.
 func BuildPMCommand(wrapper byte, commands ...[]byte) []byte {
 	// Calculate total size of inner commands
 	totalSize := 0
@@ -298,23 +349,43 @@ func BuildPMCommand(wrapper byte, commands ...[]byte) []byte {
 	return result
 }
 
-// stuffAndWrite writes a byte with byte stuffing if necessary
-func stuffAndWrite(buf *bytes.Buffer, b byte) {
+// BuildPMCommandChecked is BuildPMCommand but returns ErrCommandTooLong rather
+// than wrapping the size byte when the commands exceed 255 bytes
+func BuildPMCommandChecked(wrapper byte, commands ...[]byte) ([]byte, error) {
+	totalSize := 0
+	for _, cmd := range commands {
+		totalSize += len(cmd)
+	}
+	if totalSize > maxCommandData {
+		return nil, fmt.Errorf("%w: %d bytes", ErrCommandTooLong, totalSize)
+	}
+	return BuildPMCommand(wrapper, commands...), nil
+}
+
+// StuffedLen returns the length of data after byte stuffing
+func StuffedLen(data []byte) int {
+	n := len(data)
+	for _, b := range data {
+		if b >= ExtendedFrameStartFlag && b <= ByteStuffingFlag {
+			n++
+		}
+	}
+	return n
+}
+
+// appendStuffed appends a byte with byte stuffing if necessary
+func appendStuffed(dst []byte, b byte) []byte {
 	switch b {
 	case ExtendedFrameStartFlag:
-		buf.WriteByte(ByteStuffingFlag)
-		buf.WriteByte(0x00)
+		return append(dst, ByteStuffingFlag, 0x00)
 	case StandardFrameStartFlag:
-		buf.WriteByte(ByteStuffingFlag)
-		buf.WriteByte(0x01)
+		return append(dst, ByteStuffingFlag, 0x01)
 	case StopFrameFlag:
-		buf.WriteByte(ByteStuffingFlag)
-		buf.WriteByte(0x02)
+		return append(dst, ByteStuffingFlag, 0x02)
 	case ByteStuffingFlag:
-		buf.WriteByte(ByteStuffingFlag)
-		buf.WriteByte(0x03)
+		return append(dst, ByteStuffingFlag, 0x03)
 	default:
-		buf.WriteByte(b)
+		return append(dst, b)
 	}
 }
 
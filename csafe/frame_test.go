@@ -0,0 +1,377 @@
+package csafe
+
+import (
+	"bytes"
+	"testing"
+)
+
+// bufferEncodeFrame is the original bytes.Buffer encoder, kept as the
+// reference that AppendFrame and EncodeFrame must match
+func bufferEncodeFrame(f *Frame) ([]byte, error) {
+	var buf bytes.Buffer
+	stuff := func(b byte) {
+		if b >= ExtendedFrameStartFlag && b <= ByteStuffingFlag {
+			buf.WriteByte(ByteStuffingFlag)
+			buf.WriteByte(b - ExtendedFrameStartFlag)
+			return
+		}
+		buf.WriteByte(b)
+	}
+
+	if f.Extended {
+		buf.WriteByte(ExtendedFrameStartFlag)
+		stuff(f.Destination)
+		stuff(f.Source)
+	} else {
+		buf.WriteByte(StandardFrameStartFlag)
+	}
+	checksum := byte(0)
+	for _, b := range f.Contents {
+		checksum ^= b
+		stuff(b)
+	}
+	stuff(checksum)
+	buf.WriteByte(StopFrameFlag)
+
+	if buf.Len() > MaxFrameLength {
+		return nil, ErrFrameTooLong
+	}
+	return buf.Bytes(), nil
+}
+
+// snapshotCommands is a 16-command poll like the one GetWorkoutSnapshot sends,
+// with a few data commands whose bytes need stuffing
+func snapshotCommands() [][]byte {
+	return [][]byte{
+		{0xA0}, {0xA1}, {0xA3}, {0xA4}, {0xA5}, {0xA6}, {0xA7}, {0xA8},
+		{0xB0}, {0xB4}, {0xB5}, {0xB6}, {0xBF},
+		{0x1A, 0x03, 0xF0, 0xF1, 0xF2},
+		{0x21, 0x03, 0x01, 0xF3, 0x00},
+		{0x10, 0x00},
+	}
+}
+
+func frameCases() []*Frame {
+	var contents []byte
+	for _, c := range snapshotCommands() {
+		contents = append(contents, c...)
+	}
+	allFlags := bytes.Repeat([]byte{0xF0, 0xF1, 0xF2, 0xF3}, 10)
+	return []*Frame{
+		{Contents: []byte{}},
+		{Contents: []byte{CmdGetStatus}},
+		{Contents: contents},
+		{Contents: allFlags},
+		{Extended: true, Destination: 0xFD, Source: 0xF0, Contents: contents},
+		{Contents: bytes.Repeat([]byte{0xF1}, MaxFrameLength)},
+	}
+}
+
+func TestAppendFrameMatchesBufferEncoder(t *testing.T) {
+	for i, f := range frameCases() {
+		want, wantErr := bufferEncodeFrame(f)
+
+		got, err := EncodeFrame(f)
+		if err != wantErr || !bytes.Equal(got, want) {
+			t.Errorf("case %d: EncodeFrame() = % X, %v, want % X, %v", i, got, err, want, wantErr)
+		}
+
+		// Appending after existing bytes must leave them untouched
+		prefix := []byte{0xAA, 0xBB}
+		appended, err := AppendFrame(append([]byte(nil), prefix...), f)
+		if err != wantErr {
+			t.Errorf("case %d: AppendFrame() error = %v, want %v", i, err, wantErr)
+			continue
+		}
+		if !bytes.Equal(appended, append(prefix, want...)) {
+			t.Errorf("case %d: AppendFrame() = % X, want % X", i, appended, append(prefix, want...))
+		}
+	}
+}
+
+func TestBuildCommandIntoMatchesBuildCommand(t *testing.T) {
+	tests := []struct {
+		cmd  byte
+		data []byte
+	}{
+		{0x80, nil},
+		{0xA0, nil},
+		{0x10, nil},
+		{0x21, []byte{0x01, 0x02, 0x03}},
+		{0xA0, []byte{0x05}},
+		{0x1A, []byte{0xF0, 0xF1, 0xF2, 0xF3}},
+	}
+
+	var buf []byte
+	var want []byte
+	for _, tt := range tests {
+		single := BuildCommand(tt.cmd, tt.data...)
+		if got := BuildCommandInto(nil, tt.cmd, tt.data...); !bytes.Equal(got, single) {
+			t.Errorf("BuildCommandInto(0x%02X) = % X, want % X", tt.cmd, got, single)
+		}
+		buf = BuildCommandInto(buf, tt.cmd, tt.data...)
+		want = append(want, single...)
+	}
+	if !bytes.Equal(buf, want) {
+		t.Errorf("appended commands = % X, want % X", buf, want)
+	}
+}
+
+func BenchmarkEncodeFrameBuffer(b *testing.B) {
+	f := frameCases()[2]
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := bufferEncodeFrame(f); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeFrame(b *testing.B) {
+	f := frameCases()[2]
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := EncodeFrame(f); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendFrameReused(b *testing.B) {
+	f := frameCases()[2]
+	var buf []byte
+	b.ReportAllocs()
+	for b.Loop() {
+		var err error
+		if buf, err = AppendFrame(buf[:0], f); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildCommand(b *testing.B) {
+	cmds := snapshotCommands()
+	b.ReportAllocs()
+	for b.Loop() {
+		var contents []byte
+		for _, c := range cmds {
+			contents = append(contents, BuildCommand(c[0], c[min(2, len(c)):]...)...)
+		}
+	}
+}
+
+func BenchmarkBuildCommandInto(b *testing.B) {
+	cmds := snapshotCommands()
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for b.Loop() {
+		buf = buf[:0]
+		for _, c := range cmds {
+			buf = BuildCommandInto(buf, c[0], c[min(2, len(c)):]...)
+		}
+	}
+}
+
+// appendParseCommands is the original parser that grows the slice as it goes,
+// kept as the reference for the pre-sized parseCommands
+func appendParseCommands(data []byte) []CommandResponse {
+	var responses []CommandResponse
+	for offset := 0; offset < len(data); {
+		cmd := data[offset]
+		offset++
+		if offset >= len(data) {
+			responses = append(responses, CommandResponse{Command: cmd})
+			break
+		}
+		byteCount := data[offset]
+		offset++
+		var cmdData []byte
+		if byteCount > 0 {
+			end := min(offset+int(byteCount), len(data))
+			cmdData = data[offset:end]
+			offset = end
+		}
+		responses = append(responses, CommandResponse{Command: cmd, ByteCount: byteCount, Data: cmdData})
+	}
+	return responses
+}
+
+// snapshotResponse is a response to the 16-command snapshot poll, every
+// command wrapped in a PM data request
+func snapshotResponse() []byte {
+	var inner []byte
+	for i := range 16 {
+		inner = append(inner, 0xA0+byte(i), 0x04, byte(i), 0x00, 0x01, 0x02)
+	}
+	return append([]byte{0x01, CmdGetPMData, byte(len(inner))}, inner...)
+}
+
+func TestParseCommandsMatchesAppendParser(t *testing.T) {
+	inputs := [][]byte{
+		nil,
+		{0x80},
+		{0x80, 0x00},
+		{0x91, 0x03, 0x01, 0x02, 0x03, 0x92, 0x00},
+		{0x91, 0x05, 0x01, 0x02},
+		{0x91, 0x01, 0x01, 0x93},
+		snapshotResponse()[1:],
+		snapshotResponse()[3:],
+	}
+
+	for _, data := range inputs {
+		got, err := parseCommands(data, false)
+		if err != nil {
+			t.Fatalf("parseCommands(% X): %v", data, err)
+		}
+		want := appendParseCommands(data)
+		if len(got) != len(want) {
+			t.Errorf("parseCommands(% X) found %d commands, want %d", data, len(got), len(want))
+			continue
+		}
+		for i := range want {
+			if got[i].Command != want[i].Command || got[i].ByteCount != want[i].ByteCount ||
+				!bytes.Equal(got[i].Data, want[i].Data) {
+				t.Errorf("parseCommands(% X)[%d] = %+v, want %+v", data, i, got[i], want[i])
+			}
+		}
+		if len(data) > 0 && cap(got) != len(got) {
+			t.Errorf("parseCommands(% X) capacity %d, want exactly %d", data, cap(got), len(got))
+		}
+	}
+}
+
+func TestParseResponseSnapshot(t *testing.T) {
+	resp, err := ParseResponse(snapshotResponse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.CommandData) != 1 {
+		t.Fatalf("got %d wrapper responses, want 1", len(resp.CommandData))
+	}
+	pm := resp.CommandData[0].PMResponses
+	if len(pm) != 16 {
+		t.Fatalf("got %d PM responses, want 16", len(pm))
+	}
+	for i, r := range pm {
+		want := []byte{byte(i), 0x00, 0x01, 0x02}
+		if r.Command != 0xA0+byte(i) || !bytes.Equal(r.Data, want) {
+			t.Errorf("PM response %d = 0x%02X % X, want 0x%02X % X", i, r.Command, r.Data, 0xA0+i, want)
+		}
+	}
+}
+
+func BenchmarkParseCommandsAppend(b *testing.B) {
+	data := snapshotResponse()[3:]
+	b.ReportAllocs()
+	for b.Loop() {
+		appendParseCommands(data)
+	}
+}
+
+func BenchmarkParseCommands(b *testing.B) {
+	data := snapshotResponse()[3:]
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := parseCommands(data, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseResponseSnapshot(b *testing.B) {
+	data := snapshotResponse()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ParseResponse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	flags := []byte{ExtendedFrameStartFlag, StandardFrameStartFlag, StopFrameFlag, ByteStuffingFlag}
+
+	tests := []struct {
+		name  string
+		frame Frame
+	}{
+		{"empty", Frame{Contents: []byte{}}},
+		{"plain", Frame{Contents: []byte{CmdGetStatus, 0x91, 0x00}}},
+		{"every flag byte", Frame{Contents: flags}},
+		{"flags between data", Frame{Contents: []byte{0x01, 0xF0, 0x02, 0xF1, 0x03, 0xF2, 0x04, 0xF3}}},
+		// The checksum of these contents is 0xF1, so it must be stuffed too
+		{"stuffed checksum", Frame{Contents: []byte{0xF1}}},
+		{"extended", Frame{Extended: true, Destination: 0xFD, Source: 0x00, Contents: []byte{CmdGetStatus}}},
+		{"extended flag addresses", Frame{Extended: true, Destination: 0xF2, Source: 0xF3, Contents: flags}},
+		{"extended empty", Frame{Extended: true, Destination: 0xF0, Source: 0xF1, Contents: []byte{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeFrame(&tt.frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Only the outer flags may appear unstuffed on the wire
+			for i, b := range encoded[1 : len(encoded)-1] {
+				if b == ExtendedFrameStartFlag || b == StandardFrameStartFlag || b == StopFrameFlag {
+					t.Errorf("unstuffed flag 0x%02X at offset %d of % X", b, i+1, encoded)
+				}
+			}
+
+			got, err := RoundTrip(&tt.frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Extended != tt.frame.Extended || got.Destination != tt.frame.Destination ||
+				got.Source != tt.frame.Source || !bytes.Equal(got.Contents, tt.frame.Contents) {
+				t.Errorf("RoundTrip() = %+v, want %+v", *got, tt.frame)
+			}
+		})
+	}
+}
+
+func TestDecodeFrameErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"too short", []byte{0xF1, 0xF2}, ErrFrameTooShort},
+		{"bad start", []byte{0x01, 0x00, 0xF2}, ErrInvalidStartFlag},
+		{"bad stop", []byte{0xF1, 0x00, 0x00}, ErrInvalidStopFlag},
+		{"bad checksum", []byte{0xF1, 0x80, 0x81, 0xF2}, ErrInvalidChecksum},
+		{"bad stuffing", []byte{0xF1, 0xF3, 0x07, 0xF2}, ErrInvalidStuffByte},
+		{"extended without addresses", []byte{0xF0, 0x00, 0xF2}, ErrFrameTooShort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeFrame(tt.data); err != tt.want {
+				t.Errorf("DecodeFrame(% X) error = %v, want %v", tt.data, err, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(false, byte(0), byte(0), []byte{CmdGetStatus})
+	f.Add(true, byte(0xF0), byte(0xF3), []byte{0xF0, 0xF1, 0xF2, 0xF3})
+	f.Fuzz(func(t *testing.T, extended bool, dst, src byte, contents []byte) {
+		frame := &Frame{Extended: extended, Destination: dst, Source: src, Contents: contents}
+		got, err := RoundTrip(frame)
+		if err == ErrFrameTooLong {
+			return
+		}
+		if err != nil {
+			t.Fatalf("RoundTrip(%+v): %v", frame, err)
+		}
+		if !extended {
+			dst, src = 0, 0
+		}
+		if got.Extended != extended || got.Destination != dst || got.Source != src ||
+			!bytes.Equal(got.Contents, contents) {
+			t.Errorf("RoundTrip() = %+v, want %+v", *got, *frame)
+		}
+	})
+}
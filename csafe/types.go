@@ -20,9 +20,9 @@ const (
 const (
 	ManufacturerID      byte = 22
 	ClassID             byte = 2
-	ModelPM3            byte = 3
-	ModelPM4            byte = 4
-	ModelPM5            byte = 5
+	ModelPM3            PMModel = 3
+	ModelPM4            PMModel = 4
+	ModelPM5            PMModel = 5
 	MaxFrameLength      int  = 120
 	MinInterframeGapMs  int  = 50
 )
@@ -72,6 +72,159 @@ func (s OperationalState) String() string {
 	return "Unknown"
 }
 
+// IsInBootloader reports whether the PM is stuck in, or headed for, the
+// firmware bootloader, where normal commands fail
+func (s OperationalState) IsInBootloader() bool {
+	return s == OperationalStateInvokeBootloader || s == OperationalStateFWUpdate
+}
+
+// PowerUpState reports how the PM last started
+type PowerUpState byte
+
+const (
+	PowerUpStatePowerOn PowerUpState = 0 // Cold start from off
+	PowerUpStateWakeup  PowerUpState = 1 // Woken from sleep
+	PowerUpStateReset   PowerUpState = 2 // Restarted by a reset or watchdog
+)
+
+func (s PowerUpState) String() string {
+	names := map[PowerUpState]string{
+		PowerUpStatePowerOn: "Power On",
+		PowerUpStateWakeup:  "Wakeup",
+		PowerUpStateReset:   "Reset",
+	}
+	if name, ok := names[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// CommunicationState represents the state of the PM communication stack
+type CommunicationState byte
+
+const (
+	CommunicationStateInit  CommunicationState = 0 // Still starting; commands may be dropped
+	CommunicationStateReady CommunicationState = 1
+	CommunicationStateBusy  CommunicationState = 2
+	CommunicationStateError CommunicationState = 3
+)
+
+func (s CommunicationState) String() string {
+	names := map[CommunicationState]string{
+		CommunicationStateInit:  "Init",
+		CommunicationStateReady: "Ready",
+		CommunicationStateBusy:  "Busy",
+		CommunicationStateError: "Error",
+	}
+	if name, ok := names[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// LogCardState reports whether a LogCard (USB stick on the PM5) is inserted
+type LogCardState byte
+
+const (
+	LogCardStateNotPresent LogCardState = 0
+	LogCardStatePresent    LogCardState = 1
+)
+
+func (s LogCardState) String() string {
+	names := map[LogCardState]string{
+		LogCardStateNotPresent: "Not Present",
+		LogCardStatePresent:    "Present",
+	}
+	if name, ok := names[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// LogCardStatus reports what an inserted LogCard is doing
+type LogCardStatus byte
+
+const (
+	LogCardStatusIdle  LogCardStatus = 0
+	LogCardStatusBusy  LogCardStatus = 1 // Reading or writing; log refreshes are rejected
+	LogCardStatusFull  LogCardStatus = 2
+	LogCardStatusError LogCardStatus = 3
+)
+
+func (s LogCardStatus) String() string {
+	names := map[LogCardStatus]string{
+		LogCardStatusIdle:  "Idle",
+		LogCardStatusBusy:  "Busy",
+		LogCardStatusFull:  "Full",
+		LogCardStatusError: "Error",
+	}
+	if name, ok := names[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// ErrorType classifies the error value logged by the PM
+type ErrorType byte
+
+const (
+	ErrorTypeNone    ErrorType = 0
+	ErrorTypeWarning ErrorType = 1
+	ErrorTypeError   ErrorType = 2
+	ErrorTypeFatal   ErrorType = 3
+)
+
+func (t ErrorType) String() string {
+	names := map[ErrorType]string{
+		ErrorTypeNone:    "None",
+		ErrorTypeWarning: "Warning",
+		ErrorTypeError:   "Error",
+		ErrorTypeFatal:   "Fatal",
+	}
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// StatusType classifies the status value reported by the PM
+type StatusType byte
+
+const (
+	StatusTypeNone    StatusType = 0
+	StatusTypeInfo    StatusType = 1
+	StatusTypeWarning StatusType = 2
+	StatusTypeFault   StatusType = 3
+)
+
+func (t StatusType) String() string {
+	names := map[StatusType]string{
+		StatusTypeNone:    "None",
+		StatusTypeInfo:    "Info",
+		StatusTypeWarning: "Warning",
+		StatusTypeFault:   "Fault",
+	}
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// PMModel identifies the performance monitor generation reported by GetVersion
+type PMModel byte
+
+func (m PMModel) String() string {
+	names := map[PMModel]string{
+		ModelPM3: "PM3",
+		ModelPM4: "PM4",
+		ModelPM5: "PM5",
+	}
+	if name, ok := names[m]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
 // ErgMachineType represents the type of ergometer machine
 type ErgMachineType byte
 
@@ -99,6 +252,7 @@ const (
 	ErgMachineTypeMultiErgRow     ErgMachineType = 224
 	ErgMachineTypeMultiErgSki     ErgMachineType = 225
 	ErgMachineTypeMultiErgBike    ErgMachineType = 226
+	ErgMachineTypeUnknown         ErgMachineType = 255 // Not reported by the PM; used when the type could not be read
 )
 
 func (t ErgMachineType) String() string {
@@ -133,6 +287,25 @@ func (t ErgMachineType) String() string {
 	return "Unknown"
 }
 
+// IsSki reports whether the machine is a SkiErg
+func (t ErgMachineType) IsSki() bool {
+	switch t {
+	case ErgMachineTypeStaticSki, ErgMachineTypeSkiSimulator, ErgMachineTypeMultiErgSki:
+		return true
+	}
+	return false
+}
+
+// IsBike reports whether the machine is a BikeErg
+func (t ErgMachineType) IsBike() bool {
+	switch t {
+	case ErgMachineTypeBike, ErgMachineTypeBikeArms, ErgMachineTypeBikeNoArms,
+		ErgMachineTypeBikeSimulator, ErgMachineTypeMultiErgBike:
+		return true
+	}
+	return false
+}
+
 // WorkoutType represents the type of workout
 type WorkoutType byte
 
@@ -294,6 +467,88 @@ func (s StrokeState) String() string {
 	return "Unknown"
 }
 
+// RaceType represents the type of race
+type RaceType byte
+
+const (
+	RaceTypeFixedDistSingleErg      RaceType = 0
+	RaceTypeFixedTimeSingleErg      RaceType = 1
+	RaceTypeFixedDistTeamErg        RaceType = 2
+	RaceTypeFixedTimeTeamErg        RaceType = 3
+	RaceTypeWorkoutRaceStart        RaceType = 4
+	RaceTypeFixedCalSingleErg       RaceType = 5
+	RaceTypeFixedCalTeamErg         RaceType = 6
+	RaceTypeFixedDistRelaySingleErg RaceType = 7
+	RaceTypeFixedTimeRelaySingleErg RaceType = 8
+	RaceTypeFixedCalRelaySingleErg  RaceType = 9
+	RaceTypeFixedDistRelayTeamErg   RaceType = 10
+	RaceTypeFixedTimeRelayTeamErg   RaceType = 11
+	RaceTypeFixedCalRelayTeamErg    RaceType = 12
+)
+
+func (t RaceType) String() string {
+	names := map[RaceType]string{
+		RaceTypeFixedDistSingleErg:      "Fixed Distance (Single Erg)",
+		RaceTypeFixedTimeSingleErg:      "Fixed Time (Single Erg)",
+		RaceTypeFixedDistTeamErg:        "Fixed Distance (Team Erg)",
+		RaceTypeFixedTimeTeamErg:        "Fixed Time (Team Erg)",
+		RaceTypeWorkoutRaceStart:        "Workout Race Start",
+		RaceTypeFixedCalSingleErg:       "Fixed Calorie (Single Erg)",
+		RaceTypeFixedCalTeamErg:         "Fixed Calorie (Team Erg)",
+		RaceTypeFixedDistRelaySingleErg: "Fixed Distance Relay (Single Erg)",
+		RaceTypeFixedTimeRelaySingleErg: "Fixed Time Relay (Single Erg)",
+		RaceTypeFixedCalRelaySingleErg:  "Fixed Calorie Relay (Single Erg)",
+		RaceTypeFixedDistRelayTeamErg:   "Fixed Distance Relay (Team Erg)",
+		RaceTypeFixedTimeRelayTeamErg:   "Fixed Time Relay (Team Erg)",
+		RaceTypeFixedCalRelayTeamErg:    "Fixed Calorie Relay (Team Erg)",
+	}
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// RaceOperationType represents the race state machine operation
+type RaceOperationType byte
+
+const (
+	RaceOperationTypeDisable              RaceOperationType = 0
+	RaceOperationTypeParticipationRequest RaceOperationType = 1
+	RaceOperationTypeSleep                RaceOperationType = 2
+	RaceOperationTypeErgInit              RaceOperationType = 3
+	RaceOperationTypePhyAddrInit          RaceOperationType = 4
+	RaceOperationTypeRaceWarmup           RaceOperationType = 5
+	RaceOperationTypeRaceInit             RaceOperationType = 6
+	RaceOperationTypeTimeSync             RaceOperationType = 7
+	RaceOperationTypeRaceWaitToStart      RaceOperationType = 8
+	RaceOperationTypeStart                RaceOperationType = 9
+	RaceOperationTypeFalseStart           RaceOperationType = 10
+	RaceOperationTypeTerminate            RaceOperationType = 11
+	RaceOperationTypeIdle                 RaceOperationType = 12
+)
+
+func (t RaceOperationType) String() string {
+	names := map[RaceOperationType]string{
+		RaceOperationTypeDisable:              "Disable",
+		RaceOperationTypeParticipationRequest: "Participation Request",
+		RaceOperationTypeSleep:                "Sleep",
+		RaceOperationTypeErgInit:              "Erg Init",
+		RaceOperationTypePhyAddrInit:          "Physical Address Init",
+		RaceOperationTypeRaceWarmup:           "Race Warmup",
+		RaceOperationTypeRaceInit:             "Race Init",
+		RaceOperationTypeTimeSync:             "Time Sync",
+		RaceOperationTypeRaceWaitToStart:      "Race Wait To Start",
+		RaceOperationTypeStart:                "Start",
+		RaceOperationTypeFalseStart:           "False Start",
+		RaceOperationTypeTerminate:            "Terminate",
+		RaceOperationTypeIdle:                 "Idle",
+	}
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
 // DurationType represents workout duration identifier
 type DurationType byte
 
@@ -304,6 +559,19 @@ const (
 	DurationTypeWattMin   DurationType = 0xC0
 )
 
+func (t DurationType) String() string {
+	names := map[DurationType]string{
+		DurationTypeTime:     "Time",
+		DurationTypeCalories: "Calories",
+		DurationTypeDistance: "Distance",
+		DurationTypeWattMin:  "Watt-Minutes",
+	}
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
 // ScreenType represents the screen type for PM commands
 type ScreenType byte
 
@@ -316,6 +584,29 @@ const (
 	ScreenTypeMfg     ScreenType = 5
 )
 
+func (t ScreenType) String() string {
+	names := map[ScreenType]string{
+		ScreenTypeNone:    "None",
+		ScreenTypeWorkout: "Workout",
+		ScreenTypeRace:    "Race",
+		ScreenTypeCSAFE:   "CSAFE",
+		ScreenTypeDiag:    "Diagnostic",
+		ScreenTypeMfg:     "Manufacturing",
+	}
+	if name, ok := names[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// ScreenStatus reports whether the last screen state change has been processed
+type ScreenStatus byte
+
+const (
+	ScreenStatusInactive ScreenStatus = 0
+	ScreenStatusPending  ScreenStatus = 1
+)
+
 // ScreenValueWorkout represents screen values for workout type
 type ScreenValueWorkout byte
 
@@ -391,6 +682,24 @@ const (
 	StateMachineOffLine byte = 0x09
 )
 
+// Gender represents the user gender used for calorie calculations
+type Gender byte
+
+const (
+	GenderMale   Gender = 0
+	GenderFemale Gender = 1
+)
+
+func (g Gender) String() string {
+	switch g {
+	case GenderMale:
+		return "Male"
+	case GenderFemale:
+		return "Female"
+	}
+	return "Unknown"
+}
+
 // WorkoutNumber represents predefined workout numbers
 type WorkoutNumber byte
 
@@ -414,4 +723,8 @@ const (
 	UnitsKm       byte = 0x21 // Kilometers
 	UnitsWatt     byte = 0x58 // Watts
 	UnitsSeconds  byte = 0x00 // Seconds
+	UnitsLb       byte = 0x06 // Pounds
+	UnitsLbTenths byte = 0x07 // 0.1 pounds
+	UnitsKg       byte = 0x27 // Kilograms
+	UnitsKgTenths byte = 0x28 // 0.1 kilograms
 )
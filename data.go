@@ -1,8 +1,13 @@
 package pm5
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/danhigham/pm5/csafe"
@@ -38,29 +43,40 @@ func WattsToPace(watts float64) float64 {
 	return 500.0 * math.Pow(WattsRef/watts, 1.0/3.0)
 }
 
-// CaloriesPerHourToPace converts calories per hour to pace (seconds per 500m)
-func CaloriesPerHourToPace(calsPerHour float64) float64 {
-	if calsPerHour <= 0 {
+// Calorie conversion constants from the Concept2 formula
+// cal/hr = watts * 4 * 0.8604 + 300
+const (
+	caloriesPerWattHour = 4.0 * 0.8604
+	caloriesRestPerHour = 300.0
+)
+
+// WattsToCaloriesPerHour converts watts to calories per hour using the
+// Concept2 formula (200W = 988.3 cal/hr)
+func WattsToCaloriesPerHour(watts float64) float64 {
+	if watts <= 0 {
 		return 0
 	}
-	// Formula from Concept2:
-	// cal/hr = (watts * 4 + 350) / 0.8604
-	// Solving for watts: watts = (cal/hr * 0.8604 - 350) / 4
-	watts := (calsPerHour*0.8604 - 350.0) / 4.0
+	return watts*caloriesPerWattHour + caloriesRestPerHour
+}
+
+// CaloriesPerHourToWatts converts calories per hour to watts, the inverse of
+// WattsToCaloriesPerHour
+func CaloriesPerHourToWatts(calsPerHour float64) float64 {
+	watts := (calsPerHour - caloriesRestPerHour) / caloriesPerWattHour
 	if watts <= 0 {
 		return 0
 	}
-	return WattsToPace(watts)
+	return watts
+}
+
+// CaloriesPerHourToPace converts calories per hour to pace (seconds per 500m)
+func CaloriesPerHourToPace(calsPerHour float64) float64 {
+	return WattsToPace(CaloriesPerHourToWatts(calsPerHour))
 }
 
 // PaceToCaloriesPerHour converts pace (seconds per 500m) to calories per hour
 func PaceToCaloriesPerHour(paceSeconds float64) float64 {
-	watts := PaceToWatts(paceSeconds)
-	if watts <= 0 {
-		return 0
-	}
-	// cal/hr = (watts * 4 + 350) / 0.8604
-	return (watts*4.0 + 350.0) / 0.8604
+	return WattsToCaloriesPerHour(PaceToWatts(paceSeconds))
 }
 
 // HundredthsToTime converts hundredths of seconds to a time.Duration
@@ -114,6 +130,172 @@ func FormatDistance(tenths uint32) string {
 	return fmt.Sprintf("%.1f m", meters)
 }
 
+// ParsePace parses a pace as formatted by FormatPace ("1:52.3"), without the
+// fraction ("1:52"), or as bare seconds ("112.3"), returning hundredths of seconds
+// A fraction of up to two digits is accepted; this is the unit SetTargetPaceTime takes.
+func ParsePace(s string) (uint32, error) {
+	t := strings.TrimSpace(s)
+	var hundredths uint64
+	if minutesPart, secondsPart, ok := strings.Cut(t, ":"); ok {
+		seconds, ok := parseFixed(secondsPart, 2, 2)
+		if !ok || !allDigits(minutesPart) || seconds >= 6000 {
+			return 0, fmt.Errorf("invalid pace %q: want M:SS.t, M:SS, or seconds", s)
+		}
+		minutes, err := strconv.ParseUint(minutesPart, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid pace %q: %w", s, err)
+		}
+		hundredths = minutes*6000 + seconds
+	} else {
+		seconds, ok := parseFixed(t, 0, 2)
+		if !ok {
+			return 0, fmt.Errorf("invalid pace %q: want M:SS.t, M:SS, or seconds", s)
+		}
+		hundredths = seconds
+	}
+
+	if hundredths > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid pace %q: out of range", s)
+	}
+	return uint32(hundredths), nil
+}
+
+// ParseTime parses a time as formatted by FormatTime ("1:02:03.45" or "2:03.45"),
+// with the fraction optional ("7:30"), returning hundredths of seconds
+// Bare numbers are rejected since they could be seconds or minutes.
+func ParseTime(s string) (uint32, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	invalid := fmt.Errorf("invalid time %q: want H:MM:SS.hh or M:SS.hh", s)
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, invalid
+	}
+
+	seconds, ok := parseFixed(parts[len(parts)-1], 2, 2)
+	if !ok || seconds >= 6000 {
+		return 0, invalid
+	}
+
+	var hours, minutes uint64
+	if len(parts) == 3 {
+		h, okH := parseFixed(parts[0], 0, 0)
+		m, okM := parseFixed(parts[1], 2, 0)
+		if !okH || !okM || m >= 60 {
+			return 0, invalid
+		}
+		hours, minutes = h, m
+	} else {
+		m, ok := parseFixed(parts[0], 0, 0)
+		if !ok {
+			return 0, invalid
+		}
+		minutes = m
+	}
+
+	hundredths := hours*360000 + minutes*6000 + seconds
+	if hundredths > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid time %q: out of range", s)
+	}
+	return uint32(hundredths), nil
+}
+
+// ParseDistance parses a distance with a unit, "5000 m" or "5.00 km" (the
+// space is optional), returning tenths of meters
+// Bare numbers and other units are rejected, as is precision finer than 0.1m.
+// SetWorkoutDuration takes whole meters, so divide the result by 10 for it.
+func ParseDistance(s string) (uint32, error) {
+	t := strings.TrimSpace(s)
+	var tenths uint64
+	var ok bool
+	switch {
+	case strings.HasSuffix(t, "km"):
+		tenths, ok = parseFixed(strings.TrimSpace(strings.TrimSuffix(t, "km")), 0, 4)
+	case strings.HasSuffix(t, "m"):
+		tenths, ok = parseFixed(strings.TrimSpace(strings.TrimSuffix(t, "m")), 0, 1)
+	}
+	if !ok {
+		return 0, fmt.Errorf("invalid distance %q: want a number followed by m or km", s)
+	}
+	if tenths > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid distance %q: out of range", s)
+	}
+	return uint32(tenths), nil
+}
+
+// parseFixed parses an unsigned decimal with at most fracDigits fraction digits
+// into an integer scaled by 10^fracDigits (parseFixed("52.3", 0, 2) = 5230);
+// wholeDigits, if non-zero, is the exact number of digits required before the point
+func parseFixed(s string, wholeDigits, fracDigits int) (uint64, bool) {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if !allDigits(whole) || (wholeDigits > 0 && len(whole) != wholeDigits) {
+		return 0, false
+	}
+	if hasFrac && (len(frac) > fracDigits || !allDigits(frac)) {
+		return 0, false
+	}
+
+	v, err := strconv.ParseUint(whole, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	for i := 0; i < fracDigits; i++ {
+		v *= 10
+		if i < len(frac) {
+			v += uint64(frac[i] - '0')
+		}
+	}
+	return v, true
+}
+
+// allDigits reports whether s is a non-empty run of ASCII digits
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Typical drag factor at damper settings 1 through 10, per machine family
+var (
+	rowerDamperDragFactors = [10]byte{95, 105, 115, 125, 135, 145, 155, 170, 185, 205}
+	skiDamperDragFactors   = [10]byte{75, 85, 95, 105, 115, 125, 135, 145, 160, 175}
+	bikeDamperDragFactors  = [10]byte{65, 80, 95, 110, 125, 140, 155, 170, 190, 210}
+)
+
+// DamperEstimate returns the damper setting (1-10) closest to a drag factor
+// This is only an estimate: the drag factor for a given damper setting varies
+// with flywheel cleanliness, altitude, and temperature. Returns 0 for a drag
+// factor of 0, which the PM reports before the flywheel has spun up.
+func DamperEstimate(dragFactor byte, machine csafe.ErgMachineType) int {
+	if dragFactor == 0 {
+		return 0
+	}
+
+	table := rowerDamperDragFactors
+	switch {
+	case machine.IsSki():
+		table = skiDamperDragFactors
+	case machine.IsBike():
+		table = bikeDamperDragFactors
+	}
+
+	best, bestDiff := 1, math.MaxInt
+	for i, df := range table {
+		diff := int(dragFactor) - int(df)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < bestDiff {
+			best, bestDiff = i+1, diff
+		}
+	}
+	return best
+}
+
 // ============================================================================
 // Multi-byte Data Construction (Little-Endian)
 // ============================================================================
@@ -170,6 +352,12 @@ func BytesToUint32(b []byte) uint32 {
 	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
 }
 
+// Byte order: standard CSAFE commands (GetPower, GetHorizontal, GetPace) send
+// multi-byte values LSB first, as the CSAFE specification requires. The
+// PM-specific commands carry their values MSB first and are read with the BE
+// helpers below, with one exception: the GetStrokeStats record, whose fields
+// are LSB first (see StrokeStats).
+
 // BytesToUint16BE converts big-endian byte slice to uint16
 func BytesToUint16BE(b []byte) uint16 {
 	if len(b) < 2 {
@@ -201,6 +389,7 @@ type WorkoutSnapshot struct {
 	// Distance
 	Distance          float64 // Meters
 	ProjectedDistance float64 // Meters
+	RestDistance      float64 // Meters covered during the last variable rest
 
 	// Performance
 	Pace          time.Duration // Per 500m
@@ -216,8 +405,9 @@ type WorkoutSnapshot struct {
 	CaloricBurnRate uint16 // Cals/hr
 
 	// Heart Rate
-	HeartRate    byte // BPM (255 = invalid)
-	AvgHeartRate byte
+	HeartRate     byte // BPM (HeartRateInvalid = no HR belt)
+	AvgHeartRate  byte
+	RestHeartRate byte // Average during the current rest interval
 
 	// State
 	WorkoutType   string
@@ -226,47 +416,71 @@ type WorkoutSnapshot struct {
 	RowingState   string
 	StrokeState   string
 	IntervalCount byte
+
+	// Machine
+	MachineType csafe.ErgMachineType // Selects RelevantFields
+}
+
+// snapshotCommands is the PM command batch read by GetWorkoutSnapshot
+// It is built once since the commands never change; sendPMBatch splits it
+// across frames if the PM's frame limit is too small to carry it in one
+var snapshotCommands = []pmBatchCommand{
+	{csafe.BuildCommand(csafe.PMCmdGetWorkoutType), 1},           // 0
+	{csafe.BuildCommand(csafe.PMCmdGetWorkoutState), 1},          // 1
+	{csafe.BuildCommand(csafe.PMCmdGetIntervalType), 1},          // 2
+	{csafe.BuildCommand(csafe.PMCmdGetRowingState), 1},           // 3
+	{csafe.BuildCommand(csafe.PMCmdGetStrokeState), 1},           // 4
+	{csafe.BuildCommand(csafe.PMCmdGetWorkoutIntervalCount), 1},  // 5
+	{csafe.BuildCommand(csafe.PMCmdGetWorkTime), 4},              // 6
+	{csafe.BuildCommand(csafe.PMCmdGetWorkDistance), 4},          // 7
+	{csafe.BuildCommand(csafe.PMCmdGetStroke500mPace), 4},        // 8
+	{csafe.BuildCommand(csafe.PMCmdGetTotalAvg500mPace), 4},      // 9
+	{csafe.BuildCommand(csafe.PMCmdGetStrokePower), 4},           // 10
+	{csafe.BuildCommand(csafe.PMCmdGetTotalAvgPower), 4},         // 11
+	{csafe.BuildCommand(csafe.PMCmdGetStrokeRate), 1},            // 12
+	{csafe.BuildCommand(csafe.PMCmdGetDragFactor), 1},            // 13
+	{csafe.BuildCommand(csafe.PMCmdGetTotalAvgCalories), 4},      // 14
+	{csafe.BuildCommand(csafe.PMCmdGetAvgHeartRate), 1},          // 15
+	{csafe.BuildCommand(csafe.PMCmdGetTotalAvgStrokeRate), 1},    // 16
+	{csafe.BuildCommand(csafe.PMCmdGetStrokeCaloricBurnRate), 4}, // 17
+	{csafe.BuildCommand(csafe.PMCmdGetRestAvgHeartRate), 1},      // 18
+	{csafe.BuildCommand(csafe.PMCmdGetLastRestDistance), 2},      // 19
+}
+
+// snapshotExtraCommands are the standard CSAFE commands (not PM-specific)
+// appended to the snapshot batch
+var snapshotExtraCommands = []pmBatchCommand{
+	{[]byte{csafe.CmdGetHRCur}, 1},
 }
 
 // GetWorkoutSnapshot returns a complete snapshot of the current workout
-// This uses a single batched CSAFE command for efficiency
+// This uses a single batched CSAFE command for efficiency, split across multiple
+// frames only when the PM's frame limit requires it
 func (p *PM5) GetWorkoutSnapshot() (*WorkoutSnapshot, error) {
+	return p.GetWorkoutSnapshotCtx(context.Background())
+}
+
+// GetWorkoutSnapshotCtx is GetWorkoutSnapshot with cancellation
+// If ctx is canceled while waiting for the PM, ctx.Err() is returned promptly
+func (p *PM5) GetWorkoutSnapshotCtx(ctx context.Context) (*WorkoutSnapshot, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	snapshot := &WorkoutSnapshot{}
-
-	// Build all PM commands in a single batch
-	pmCmds := [][]byte{
-		csafe.BuildCommand(csafe.PMCmdGetWorkoutType),          // 0
-		csafe.BuildCommand(csafe.PMCmdGetWorkoutState),         // 1
-		csafe.BuildCommand(csafe.PMCmdGetIntervalType),         // 2
-		csafe.BuildCommand(csafe.PMCmdGetRowingState),          // 3
-		csafe.BuildCommand(csafe.PMCmdGetStrokeState),          // 4
-		csafe.BuildCommand(csafe.PMCmdGetWorkoutIntervalCount), // 5
-		csafe.BuildCommand(csafe.PMCmdGetWorkTime),             // 6
-		csafe.BuildCommand(csafe.PMCmdGetWorkDistance),         // 7
-		csafe.BuildCommand(csafe.PMCmdGetStroke500mPace),       // 8
-		csafe.BuildCommand(csafe.PMCmdGetTotalAvg500mPace),     // 9
-		csafe.BuildCommand(csafe.PMCmdGetStrokePower),          // 10
-		csafe.BuildCommand(csafe.PMCmdGetTotalAvgPower),        // 11
-		csafe.BuildCommand(csafe.PMCmdGetStrokeRate),           // 12
-		csafe.BuildCommand(csafe.PMCmdGetDragFactor),           // 13
-		csafe.BuildCommand(csafe.PMCmdGetTotalAvgCalories),     // 14
-		csafe.BuildCommand(csafe.PMCmdGetAvgHeartRate),         // 15
-	}
-
-	// Add standard CSAFE heart rate command (not PM-specific)
-	contents := csafe.BuildPMCommand(csafe.CmdGetPMData, pmCmds...)
-	contents = append(contents, csafe.CmdGetHRCur)
-
-	resp, err := p.sendCommand(contents)
+	// Cached after the first snapshot, so this costs a frame only once. The
+	// machine type only filters fields, so failing to read it is not fatal.
+	machine, err := p.ergMachineType(ctx)
+	if err != nil {
+		machine = csafe.ErgMachineTypeUnknown
+	}
+	snapshot := &WorkoutSnapshot{MachineType: machine}
+
+	cmdData, err := p.sendPMBatch(ctx, csafe.CmdGetPMData, snapshotCommands, snapshotExtraCommands)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse the batched response
-	for _, cmdResp := range resp.CommandData {
+	for _, cmdResp := range cmdData {
 		// Handle standard CSAFE commands
 		if cmdResp.Command == csafe.CmdGetHRCur && len(cmdResp.Data) >= 1 {
 			snapshot.HeartRate = cmdResp.Data[0]
@@ -308,7 +522,8 @@ func (p *PM5) GetWorkoutSnapshot() (*WorkoutSnapshot, error) {
 				}
 			case csafe.PMCmdGetWorkDistance:
 				if len(pmResp.Data) >= 4 {
-					snapshot.Distance = float64(BytesToUint32BE(pmResp.Data[:4]))
+					// The PM reports tenths of meters
+					snapshot.Distance = TenthsToMeters(BytesToUint32BE(pmResp.Data[:4]))
 				}
 			case csafe.PMCmdGetStroke500mPace:
 				if len(pmResp.Data) >= 4 {
@@ -332,6 +547,10 @@ func (p *PM5) GetWorkoutSnapshot() (*WorkoutSnapshot, error) {
 				if len(pmResp.Data) >= 1 {
 					snapshot.StrokeRate = pmResp.Data[0]
 				}
+			case csafe.PMCmdGetTotalAvgStrokeRate:
+				if len(pmResp.Data) >= 1 {
+					snapshot.AvgStrokeRate = pmResp.Data[0]
+				}
 			case csafe.PMCmdGetDragFactor:
 				if len(pmResp.Data) >= 1 {
 					snapshot.DragFactor = pmResp.Data[0]
@@ -344,6 +563,18 @@ func (p *PM5) GetWorkoutSnapshot() (*WorkoutSnapshot, error) {
 				if len(pmResp.Data) >= 1 {
 					snapshot.AvgHeartRate = pmResp.Data[0]
 				}
+			case csafe.PMCmdGetStrokeCaloricBurnRate:
+				if len(pmResp.Data) >= 4 {
+					snapshot.CaloricBurnRate = uint16(min(BytesToUint32BE(pmResp.Data[:4]), math.MaxUint16))
+				}
+			case csafe.PMCmdGetRestAvgHeartRate:
+				if len(pmResp.Data) >= 1 {
+					snapshot.RestHeartRate = pmResp.Data[0]
+				}
+			case csafe.PMCmdGetLastRestDistance:
+				if len(pmResp.Data) >= 2 {
+					snapshot.RestDistance = float64(BytesToUint16BE(pmResp.Data[:2]))
+				}
 			}
 		}
 	}
@@ -354,13 +585,286 @@ func (p *PM5) GetWorkoutSnapshot() (*WorkoutSnapshot, error) {
 // String returns a formatted string representation of the workout snapshot
 func (s *WorkoutSnapshot) String() string {
 	return fmt.Sprintf(
-		"Time: %s | Distance: %.1fm | Pace: %s | Power: %dW | S/R: %d | HR: %d | Cals: %d",
+		"Time: %s | Distance: %.1fm | Pace: %s | Power: %dW | S/R: %d | HR: %s | Cals: %d",
 		FormatTime(TimeToHundredths(s.WorkTime)),
 		s.Distance,
 		FormatPace(TimeToHundredths(s.Pace)),
 		s.Power,
 		s.StrokeRate,
-		s.HeartRate,
+		formatHeartRate(s.HeartRate),
 		s.Calories,
 	)
 }
+
+// formatHeartRate formats a heart rate, or "--" when there is no reading
+func formatHeartRate(bpm byte) string {
+	if !IsHeartRateValid(bpm) {
+		return "--"
+	}
+	return strconv.Itoa(int(bpm))
+}
+
+// snapshotJSON is the wire form of WorkoutSnapshot: times in seconds, paces as
+// M:SS.t strings, and distance both raw and formatted
+type snapshotJSON struct {
+	ElapsedTime   float64 `json:"elapsed_s"`
+	WorkTime      float64 `json:"work_time_s"`
+	RestTime      float64 `json:"rest_time_s"`
+	ProjectedTime float64 `json:"projected_time_s"`
+
+	Distance          float64 `json:"distance_m"`
+	DistanceText      string  `json:"distance"`
+	ProjectedDistance float64 `json:"projected_distance_m"`
+	RestDistance      float64 `json:"rest_distance_m"`
+
+	Pace          string `json:"pace"`
+	AvgPace       string `json:"avg_pace"`
+	Power         uint32 `json:"power_w"`
+	AvgPower      uint32 `json:"avg_power_w"`
+	StrokeRate    byte   `json:"stroke_rate_spm"`
+	AvgStrokeRate byte   `json:"avg_stroke_rate_spm"`
+	DragFactor    byte   `json:"drag_factor"`
+
+	Calories        uint32 `json:"calories"`
+	CaloricBurnRate uint16 `json:"caloric_burn_rate_cal_hr"`
+
+	HeartRate     byte `json:"heart_rate_bpm"`
+	AvgHeartRate  byte `json:"avg_heart_rate_bpm"`
+	RestHeartRate byte `json:"rest_heart_rate_bpm"`
+
+	WorkoutType   string `json:"workout_type"`
+	WorkoutState  string `json:"workout_state"`
+	IntervalType  string `json:"interval_type"`
+	RowingState   string `json:"rowing_state"`
+	StrokeState   string `json:"stroke_state"`
+	IntervalCount byte   `json:"interval_count"`
+
+	MachineType byte `json:"machine_type"`
+}
+
+// snapshotFields lists the JSON keys of snapshotJSON in order
+var snapshotFields = []string{
+	"elapsed_s", "work_time_s", "rest_time_s", "projected_time_s",
+	"distance_m", "distance", "projected_distance_m", "rest_distance_m",
+	"pace", "avg_pace", "power_w", "avg_power_w", "stroke_rate_spm", "avg_stroke_rate_spm", "drag_factor",
+	"calories", "caloric_burn_rate_cal_hr",
+	"heart_rate_bpm", "avg_heart_rate_bpm", "rest_heart_rate_bpm",
+	"workout_type", "workout_state", "interval_type", "rowing_state", "stroke_state", "interval_count",
+	"machine_type",
+}
+
+// Snapshot fields that apply to each kind of machine
+var (
+	// Rowers report every field
+	rowerFields = snapshotFields
+
+	// The SkiErg measures each pull on a damped flywheel, so stroke state and
+	// drag factor apply as they do on a rower
+	skiFields = withoutFields(snapshotFields)
+
+	// The BikeErg has no drag factor reading or drive/recovery stroke phases
+	bikeFields = withoutFields(snapshotFields, "drag_factor", "stroke_state")
+)
+
+// withoutFields returns fields minus the excluded keys
+func withoutFields(fields []string, excluded ...string) []string {
+	result := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !slices.Contains(excluded, f) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// RelevantFields returns the JSON keys of the snapshot fields that apply to
+// its machine type, so a generic UI can skip the rest
+// An unknown machine type returns every field.
+func (s *WorkoutSnapshot) RelevantFields() []string {
+	var fields []string
+	switch {
+	case s.MachineType.IsBike():
+		fields = bikeFields
+	case s.MachineType.IsSki():
+		fields = skiFields
+	default:
+		fields = rowerFields
+	}
+	return slices.Clone(fields)
+}
+
+// MarshalJSON encodes the snapshot with human units
+// Times are seconds, paces are M:SS.t strings, and distance is given in
+// meters alongside its formatted form.
+func (s WorkoutSnapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(snapshotJSON{
+		ElapsedTime:       s.ElapsedTime.Seconds(),
+		WorkTime:          s.WorkTime.Seconds(),
+		RestTime:          s.RestTime.Seconds(),
+		ProjectedTime:     s.ProjectedTime.Seconds(),
+		Distance:          s.Distance,
+		DistanceText:      FormatDistance(MetersToTenths(s.Distance)),
+		ProjectedDistance: s.ProjectedDistance,
+		RestDistance:      s.RestDistance,
+		Pace:              FormatPace(TimeToHundredths(s.Pace)),
+		AvgPace:           FormatPace(TimeToHundredths(s.AvgPace)),
+		Power:             s.Power,
+		AvgPower:          s.AvgPower,
+		StrokeRate:        s.StrokeRate,
+		AvgStrokeRate:     s.AvgStrokeRate,
+		DragFactor:        s.DragFactor,
+		Calories:          s.Calories,
+		CaloricBurnRate:   s.CaloricBurnRate,
+		HeartRate:         s.HeartRate,
+		AvgHeartRate:      s.AvgHeartRate,
+		RestHeartRate:     s.RestHeartRate,
+		WorkoutType:       s.WorkoutType,
+		WorkoutState:      s.WorkoutState,
+		IntervalType:      s.IntervalType,
+		RowingState:       s.RowingState,
+		StrokeState:       s.StrokeState,
+		IntervalCount:     s.IntervalCount,
+		MachineType:       byte(s.MachineType),
+	})
+}
+
+// UnmarshalJSON decodes the form written by MarshalJSON
+// Paces are restored to the tenth of a second they were written with.
+func (s *WorkoutSnapshot) UnmarshalJSON(data []byte) error {
+	var j snapshotJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	pace, err := parseJSONPace(j.Pace)
+	if err != nil {
+		return err
+	}
+	avgPace, err := parseJSONPace(j.AvgPace)
+	if err != nil {
+		return err
+	}
+
+	*s = WorkoutSnapshot{
+		ElapsedTime:       secondsToDuration(j.ElapsedTime),
+		WorkTime:          secondsToDuration(j.WorkTime),
+		RestTime:          secondsToDuration(j.RestTime),
+		ProjectedTime:     secondsToDuration(j.ProjectedTime),
+		Distance:          j.Distance,
+		ProjectedDistance: j.ProjectedDistance,
+		RestDistance:      j.RestDistance,
+		Pace:              pace,
+		AvgPace:           avgPace,
+		Power:             j.Power,
+		AvgPower:          j.AvgPower,
+		StrokeRate:        j.StrokeRate,
+		AvgStrokeRate:     j.AvgStrokeRate,
+		DragFactor:        j.DragFactor,
+		Calories:          j.Calories,
+		CaloricBurnRate:   j.CaloricBurnRate,
+		HeartRate:         j.HeartRate,
+		AvgHeartRate:      j.AvgHeartRate,
+		RestHeartRate:     j.RestHeartRate,
+		WorkoutType:       j.WorkoutType,
+		WorkoutState:      j.WorkoutState,
+		IntervalType:      j.IntervalType,
+		RowingState:       j.RowingState,
+		StrokeState:       j.StrokeState,
+		IntervalCount:     j.IntervalCount,
+		MachineType:       csafe.ErgMachineType(j.MachineType),
+	}
+	return nil
+}
+
+// secondsToDuration converts seconds to a duration, rounded to the millisecond
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds*1000)) * time.Millisecond
+}
+
+// ============================================================================
+// Workout Summary
+// ============================================================================
+
+// WorkoutSummary is the result of a completed piece, used for PR comparison
+type WorkoutSummary struct {
+	WorkoutType csafe.WorkoutType
+	Goal        uint32        // Programmed duration as from GetWorkoutDuration (meters, 0.01s, or calories)
+	Time        time.Duration // Time taken
+	Distance    float64       // Meters rowed
+}
+
+// prMetric classifies a workout type for PR comparison
+// Returns false for workouts with no like-for-like metric (just row, intervals).
+func prMetric(t csafe.WorkoutType) (byTime bool, ok bool) {
+	switch t {
+	case csafe.WorkoutTypeFixedDistNoSplits, csafe.WorkoutTypeFixedDistSplits,
+		csafe.WorkoutTypeFixedCalorieSplits:
+		return true, true
+	case csafe.WorkoutTypeFixedTimeNoSplits, csafe.WorkoutTypeFixedTimeSplits:
+		return false, true
+	}
+	return false, false
+}
+
+// samePiece reports whether two summaries are the same piece
+// Split and no-split variants of the same goal are treated as the same piece.
+func (s WorkoutSummary) samePiece(o WorkoutSummary) bool {
+	sByTime, sOK := prMetric(s.WorkoutType)
+	oByTime, oOK := prMetric(o.WorkoutType)
+	if !sOK || !oOK || sByTime != oByTime || s.Goal != o.Goal {
+		return false
+	}
+	sCal := s.WorkoutType == csafe.WorkoutTypeFixedCalorieSplits
+	oCal := o.WorkoutType == csafe.WorkoutTypeFixedCalorieSplits
+	return sCal == oCal
+}
+
+// IsPRAgainst reports whether s is a personal record against history
+// Only like-for-like pieces are compared: fixed distance and calorie pieces on
+// fastest time, fixed time pieces on most distance. The string explains the result.
+func (s WorkoutSummary) IsPRAgainst(history []WorkoutSummary) (bool, string) {
+	byTime, ok := prMetric(s.WorkoutType)
+	if !ok {
+		return false, fmt.Sprintf("%s workouts are not compared", s.WorkoutType)
+	}
+
+	var best *WorkoutSummary
+	for i := range history {
+		h := &history[i]
+		if !s.samePiece(*h) {
+			continue
+		}
+		if best == nil || (byTime && h.Time < best.Time) || (!byTime && h.Distance > best.Distance) {
+			best = h
+		}
+	}
+
+	if best == nil {
+		return false, "no comparable workouts in history"
+	}
+
+	if byTime {
+		if s.Time < best.Time {
+			return true, fmt.Sprintf("new PR: %s beats %s",
+				FormatTime(TimeToHundredths(s.Time)), FormatTime(TimeToHundredths(best.Time)))
+		}
+		return false, fmt.Sprintf("%s is behind the PR of %s",
+			FormatTime(TimeToHundredths(s.Time)), FormatTime(TimeToHundredths(best.Time)))
+	}
+	if s.Distance > best.Distance {
+		return true, fmt.Sprintf("new PR: %.1fm beats %.1fm", s.Distance, best.Distance)
+	}
+	return false, fmt.Sprintf("%.1fm is behind the PR of %.1fm", s.Distance, best.Distance)
+}
+
+// parseJSONPace parses a snapshot JSON pace; empty is zero
+func parseJSONPace(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	hundredths, err := ParsePace(s)
+	if err != nil {
+		return 0, err
+	}
+	return HundredthsToTime(hundredths), nil
+}
@@ -0,0 +1,453 @@
+package pm5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// TCX Export
+// ============================================================================
+
+// WorkoutMeta describes a recorded workout for export
+type WorkoutMeta struct {
+	StartTime time.Time // Wall-clock time of the first sample's zero elapsed time
+	Sport     string    // TCX sport: "Running", "Biking", or "Other" (default)
+	Notes     string
+}
+
+type tcxDatabase struct {
+	XMLName    xml.Name      `xml:"TrainingCenterDatabase"`
+	Xmlns      string        `xml:"xmlns,attr"`
+	XmlnsNS3   string        `xml:"xmlns:ns3,attr"`
+	Activities []tcxActivity `xml:"Activities>Activity"`
+}
+
+type tcxActivity struct {
+	Sport string `xml:"Sport,attr"`
+	ID    string `xml:"Id"`
+	Lap   tcxLap `xml:"Lap"`
+	Notes string `xml:"Notes,omitempty"`
+}
+
+type tcxLap struct {
+	StartTime        string        `xml:"StartTime,attr"`
+	TotalTimeSeconds float64       `xml:"TotalTimeSeconds"`
+	DistanceMeters   float64       `xml:"DistanceMeters"`
+	Calories         uint32        `xml:"Calories"`
+	AvgHeartRate     *tcxHeartRate `xml:"AverageHeartRateBpm,omitempty"`
+	Intensity        string        `xml:"Intensity"`
+	TriggerMethod    string        `xml:"TriggerMethod"`
+	Trackpoints      []tcxPoint    `xml:"Track>Trackpoint"`
+}
+
+type tcxHeartRate struct {
+	Value byte `xml:"Value"`
+}
+
+type tcxPoint struct {
+	Time           string        `xml:"Time"`
+	DistanceMeters float64       `xml:"DistanceMeters"`
+	HeartRate      *tcxHeartRate `xml:"HeartRateBpm,omitempty"`
+	Cadence        *byte         `xml:"Cadence,omitempty"`
+	Extensions     tcxExtensions `xml:"Extensions"`
+}
+
+type tcxExtensions struct {
+	TPX tcxTPX `xml:"ns3:TPX"`
+}
+
+type tcxTPX struct {
+	Speed float64 `xml:"ns3:Speed,omitempty"` // Meters per second
+	Watts uint32  `xml:"ns3:Watts"`
+}
+
+// lastExportSample validates export input and returns the final recorded sample
+func lastExportSample(samples []*WorkoutSnapshot, meta WorkoutMeta) (*WorkoutSnapshot, error) {
+	if meta.StartTime.IsZero() {
+		return nil, errors.New("workout start time is required")
+	}
+	for i := len(samples) - 1; i >= 0; i-- {
+		if samples[i] != nil {
+			return samples[i], nil
+		}
+	}
+	return nil, errors.New("no samples to export")
+}
+
+// validHeartRate returns the rate for TCX, or nil if no belt reading is available
+func validHeartRate(bpm byte) *tcxHeartRate {
+	if !IsHeartRateValid(bpm) {
+		return nil
+	}
+	return &tcxHeartRate{Value: bpm}
+}
+
+// WriteTCX writes recorded snapshots as a single-lap Garmin TCX activity
+// Each sample becomes a trackpoint timed at meta.StartTime plus its elapsed
+// time, with distance, heart rate, stroke rate as cadence, and power and speed
+// (derived from the 500m pace) in the activity extension.
+func WriteTCX(w io.Writer, samples []*WorkoutSnapshot, meta WorkoutMeta) error {
+	last, err := lastExportSample(samples, meta)
+	if err != nil {
+		return err
+	}
+
+	sport := meta.Sport
+	if sport == "" {
+		sport = "Other"
+	}
+	start := meta.StartTime.UTC().Format(time.RFC3339)
+
+	lap := tcxLap{
+		StartTime:        start,
+		TotalTimeSeconds: last.ElapsedTime.Seconds(),
+		DistanceMeters:   last.Distance,
+		Calories:         last.Calories,
+		AvgHeartRate:     validHeartRate(last.AvgHeartRate),
+		Intensity:        "Active",
+		TriggerMethod:    "Manual",
+		Trackpoints:      make([]tcxPoint, 0, len(samples)),
+	}
+
+	for _, s := range samples {
+		if s == nil {
+			continue
+		}
+		point := tcxPoint{
+			Time:           meta.StartTime.Add(s.ElapsedTime).UTC().Format(time.RFC3339Nano),
+			DistanceMeters: s.Distance,
+			HeartRate:      validHeartRate(s.HeartRate),
+			Extensions:     tcxExtensions{TPX: tcxTPX{Watts: s.Power}},
+		}
+		if s.StrokeRate > 0 {
+			cadence := s.StrokeRate
+			point.Cadence = &cadence
+		}
+		if s.Pace > 0 {
+			point.Extensions.TPX.Speed = 500 / s.Pace.Seconds()
+		}
+		lap.Trackpoints = append(lap.Trackpoints, point)
+	}
+
+	db := tcxDatabase{
+		Xmlns:    "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2",
+		XmlnsNS3: "http://www.garmin.com/xmlschemas/ActivityExtension/v2",
+		Activities: []tcxActivity{{
+			Sport: sport,
+			ID:    start,
+			Lap:   lap,
+			Notes: meta.Notes,
+		}},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(db); err != nil {
+		return fmt.Errorf("failed to encode TCX: %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// ============================================================================
+// FIT Export
+// ============================================================================
+
+// FIT profile constants used by WriteFIT
+const (
+	fitEpochOffset     = 631065600 // Unix time of the FIT epoch, 1989-12-31T00:00:00Z
+	fitProtocolVersion = 0x20      // 2.0
+	fitProfileVersion  = 2132      // 21.32
+
+	fitMesgFileID   = 0
+	fitMesgSession  = 18
+	fitMesgLap      = 19
+	fitMesgRecord   = 20
+	fitMesgActivity = 34
+
+	fitFileActivity      = 4
+	fitManufacturerC2    = 40 // Concept2
+	fitSportRowing       = 15
+	fitSubSportIndoorRow = 14
+	fitEventSession      = 8
+	fitEventLap          = 9
+	fitEventActivity     = 26
+	fitEventTypeStop     = 1
+
+	fitEnum   = 0x00
+	fitUint8  = 0x02
+	fitUint16 = 0x84
+	fitUint32 = 0x86
+
+	fitInvalidUint8  = 0xFF
+	fitInvalidUint16 = 0xFFFF
+)
+
+// fitCRCTable is the nibble table for the FIT CRC-16
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// fitCRC updates a FIT CRC-16 with data
+func fitCRC(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		tmp := fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc ^= tmp ^ fitCRCTable[b&0xF]
+
+		tmp = fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc ^= tmp ^ fitCRCTable[(b>>4)&0xF]
+	}
+	return crc
+}
+
+// fitField is a single field of a FIT data message
+type fitField struct {
+	num      byte
+	baseType byte
+	value    uint32
+}
+
+func (f fitField) size() byte {
+	switch f.baseType {
+	case fitUint16:
+		return 2
+	case fitUint32:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// fitEncoder accumulates FIT records, emitting each local definition once
+type fitEncoder struct {
+	buf     bytes.Buffer
+	defined [16]bool
+}
+
+// message writes a data message, preceded by its definition on first use
+// A local type must always be used with the same global message and fields.
+func (e *fitEncoder) message(local byte, global uint16, fields []fitField) {
+	if !e.defined[local] {
+		e.buf.WriteByte(0x40 | local)
+		e.buf.WriteByte(0) // Reserved
+		e.buf.WriteByte(0) // Little-endian
+		e.buf.Write(binary.LittleEndian.AppendUint16(nil, global))
+		e.buf.WriteByte(byte(len(fields)))
+		for _, f := range fields {
+			e.buf.Write([]byte{f.num, f.size(), f.baseType})
+		}
+		e.defined[local] = true
+	}
+
+	e.buf.WriteByte(local)
+	for _, f := range fields {
+		switch f.size() {
+		case 4:
+			e.buf.Write(binary.LittleEndian.AppendUint32(nil, f.value))
+		case 2:
+			e.buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(f.value)))
+		default:
+			e.buf.WriteByte(byte(f.value))
+		}
+	}
+}
+
+// fitTime converts a wall-clock time to a FIT timestamp
+func fitTime(t time.Time) uint32 {
+	return uint32(t.Unix() - fitEpochOffset)
+}
+
+// fitHeartRate returns the heart rate, or the FIT invalid value if no belt reading is available
+func fitHeartRate(bpm byte) uint32 {
+	if !IsHeartRateValid(bpm) {
+		return fitInvalidUint8
+	}
+	return uint32(bpm)
+}
+
+// WriteFIT writes recorded snapshots as a FIT indoor rowing activity
+// Each sample becomes a Record message timed at meta.StartTime plus its elapsed
+// time, with distance, power, heart rate, stroke rate as cadence, and speed
+// (derived from the 500m pace). A single Lap and Session summarize the final
+// sample. meta.Sport is ignored; the sport is always indoor rowing.
+func WriteFIT(w io.Writer, samples []*WorkoutSnapshot, meta WorkoutMeta) error {
+	last, err := lastExportSample(samples, meta)
+	if err != nil {
+		return err
+	}
+
+	start := fitTime(meta.StartTime)
+	end := fitTime(meta.StartTime.Add(last.ElapsedTime))
+	elapsedMs := uint32(last.ElapsedTime.Milliseconds())
+	distance := uint32(math.Round(last.Distance * 100))
+	calories := uint32(min(last.Calories, fitInvalidUint16-1))
+
+	var e fitEncoder
+	e.message(0, fitMesgFileID, []fitField{
+		{0, fitEnum, fitFileActivity},
+		{1, fitUint16, fitManufacturerC2},
+		{4, fitUint32, start},
+	})
+
+	for _, s := range samples {
+		if s == nil {
+			continue
+		}
+		speed := uint32(fitInvalidUint16)
+		if s.Pace > 0 {
+			speed = uint32(math.Round(500 / s.Pace.Seconds() * 1000))
+		}
+		e.message(1, fitMesgRecord, []fitField{
+			{253, fitUint32, fitTime(meta.StartTime.Add(s.ElapsedTime))},
+			{5, fitUint32, uint32(math.Round(s.Distance * 100))},
+			{7, fitUint16, min(s.Power, fitInvalidUint16-1)},
+			{3, fitUint8, fitHeartRate(s.HeartRate)},
+			{4, fitUint8, uint32(s.StrokeRate)},
+			{6, fitUint16, speed},
+		})
+	}
+
+	e.message(2, fitMesgLap, []fitField{
+		{253, fitUint32, end},
+		{0, fitEnum, fitEventLap},
+		{1, fitEnum, fitEventTypeStop},
+		{2, fitUint32, start},
+		{7, fitUint32, elapsedMs},
+		{8, fitUint32, elapsedMs},
+		{9, fitUint32, distance},
+		{11, fitUint16, calories},
+		{15, fitUint8, fitHeartRate(last.AvgHeartRate)},
+	})
+
+	e.message(3, fitMesgSession, []fitField{
+		{253, fitUint32, end},
+		{0, fitEnum, fitEventSession},
+		{1, fitEnum, fitEventTypeStop},
+		{2, fitUint32, start},
+		{5, fitEnum, fitSportRowing},
+		{6, fitEnum, fitSubSportIndoorRow},
+		{7, fitUint32, elapsedMs},
+		{8, fitUint32, elapsedMs},
+		{9, fitUint32, distance},
+		{11, fitUint16, calories},
+		{16, fitUint8, fitHeartRate(last.AvgHeartRate)},
+		{25, fitUint16, 0},
+		{26, fitUint16, 1},
+	})
+
+	e.message(4, fitMesgActivity, []fitField{
+		{253, fitUint32, end},
+		{0, fitUint32, elapsedMs},
+		{1, fitUint16, 1},
+		{2, fitEnum, 0}, // Manual
+		{3, fitEnum, fitEventActivity},
+		{4, fitEnum, fitEventTypeStop},
+	})
+
+	// 14-byte file header, data records, then a CRC over everything before it
+	file := make([]byte, 0, 14+e.buf.Len()+2)
+	file = append(file, 14, fitProtocolVersion)
+	file = binary.LittleEndian.AppendUint16(file, fitProfileVersion)
+	file = binary.LittleEndian.AppendUint32(file, uint32(e.buf.Len()))
+	file = append(file, ".FIT"...)
+	file = binary.LittleEndian.AppendUint16(file, fitCRC(0, file))
+	file = append(file, e.buf.Bytes()...)
+	file = binary.LittleEndian.AppendUint16(file, fitCRC(0, file))
+
+	_, err = w.Write(file)
+	return err
+}
+
+// ============================================================================
+// CSV Export
+// ============================================================================
+
+// SnapshotCSVHeader is the column header written by CSVRecorder
+var SnapshotCSVHeader = []string{
+	"elapsed_s", "distance_m", "pace_s_per_500m", "power_w", "stroke_rate_spm", "heart_rate_bpm", "calories",
+}
+
+// CSVRecorder writes one CSV row per WorkoutSnapshot for spreadsheet analysis
+// The header is written before the first row and every row is flushed as it is
+// recorded, so the file is usable even if the session ends abruptly. It is safe
+// for concurrent use.
+type CSVRecorder struct {
+	mu          sync.Mutex
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSVRecorder creates a recorder writing to w
+func NewCSVRecorder(w io.Writer) *CSVRecorder {
+	return &CSVRecorder{w: csv.NewWriter(w)}
+}
+
+// Record writes a row for s; nil snapshots are ignored
+// Pace and heart rate are left empty when the PM has no reading.
+func (r *CSVRecorder) Record(s *WorkoutSnapshot) error {
+	if s == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.wroteHeader {
+		if err := r.w.Write(SnapshotCSVHeader); err != nil {
+			return err
+		}
+		r.wroteHeader = true
+	}
+
+	pace := ""
+	if s.Pace > 0 {
+		pace = strconv.FormatFloat(s.Pace.Seconds(), 'f', 2, 64)
+	}
+	heartRate := ""
+	if IsHeartRateValid(s.HeartRate) {
+		heartRate = strconv.Itoa(int(s.HeartRate))
+	}
+
+	row := []string{
+		strconv.FormatFloat(s.ElapsedTime.Seconds(), 'f', 2, 64),
+		strconv.FormatFloat(s.Distance, 'f', 1, 64),
+		pace,
+		strconv.FormatUint(uint64(s.Power), 10),
+		strconv.Itoa(int(s.StrokeRate)),
+		heartRate,
+		strconv.FormatUint(uint64(s.Calories), 10),
+	}
+	if err := r.w.Write(row); err != nil {
+		return err
+	}
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// RecordAll records every snapshot from ch until it is closed, such as the
+// channel returned by Subscribe
+// It stops at the first write error.
+func (r *CSVRecorder) RecordAll(ch <-chan *WorkoutSnapshot) error {
+	for s := range ch {
+		if err := r.Record(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package pm5
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+func TestRelevantFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		machine  csafe.ErgMachineType
+		excluded []string
+	}{
+		{"rower", csafe.ErgMachineTypeStaticD, nil},
+		{"ski", csafe.ErgMachineTypeStaticSki, nil},
+		{"bike", csafe.ErgMachineTypeBike, []string{"drag_factor", "stroke_state"}},
+		{"unknown", csafe.ErgMachineTypeUnknown, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &WorkoutSnapshot{MachineType: tt.machine}
+			got := s.RelevantFields()
+
+			want := withoutFields(snapshotFields, tt.excluded...)
+			if !slices.Equal(got, want) {
+				t.Errorf("RelevantFields() = %v\nwant %v", got, want)
+			}
+			for _, f := range tt.excluded {
+				if slices.Contains(got, f) {
+					t.Errorf("%s listed for %s", f, tt.machine)
+				}
+			}
+			for _, f := range []string{"pace", "power_w", "stroke_rate_spm", "heart_rate_bpm"} {
+				if !slices.Contains(got, f) {
+					t.Errorf("%s missing for %s", f, tt.machine)
+				}
+			}
+		})
+	}
+}
+
+func TestRelevantFieldsReturnsCopy(t *testing.T) {
+	s := &WorkoutSnapshot{MachineType: csafe.ErgMachineTypeStaticD}
+	s.RelevantFields()[0] = "changed"
+	if s.RelevantFields()[0] == "changed" {
+		t.Error("RelevantFields exposes the shared field list")
+	}
+}
+
+func TestParsePace(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"1:52.3", 11230},
+		{"1:52", 11200},
+		{"1:52.34", 11234},
+		{"0:59.9", 5990},
+		{" 2:00.0 ", 12000},
+		{"112.3", 11230},
+		{"112", 11200},
+		{"10:05", 60500},
+	}
+	for _, tt := range tests {
+		got, err := ParsePace(tt.in)
+		if err != nil {
+			t.Errorf("ParsePace(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePace(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "1:5", "1:60", "1:52.345", "a:52", "1:5x", "-1:52", "1:52.", "1::52", "fast"} {
+		if _, err := ParsePace(in); err == nil {
+			t.Errorf("ParsePace(%q) succeeded, want an error", in)
+		}
+	}
+
+	// FormatPace output parses back to the same tenths
+	for _, hundredths := range []uint32{0, 5990, 11230, 60500} {
+		if got, err := ParsePace(FormatPace(hundredths)); err != nil || got != hundredths {
+			t.Errorf("ParsePace(FormatPace(%d)) = %d, %v", hundredths, got, err)
+		}
+	}
+}
+
+func TestSnapshotMachineTypeFallback(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	// The machine type lookup is rejected, the snapshot batch answered
+	queueResponse(t, dev, statusRejected)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetDragFactor, 120)))
+
+	snapshot, err := p.GetWorkoutSnapshot()
+	if err != nil {
+		t.Fatalf("GetWorkoutSnapshot: %v", err)
+	}
+	if snapshot.MachineType != csafe.ErgMachineTypeUnknown {
+		t.Errorf("MachineType = %v, want unknown", snapshot.MachineType)
+	}
+	if snapshot.DragFactor != 120 {
+		t.Errorf("DragFactor = %d, want 120", snapshot.DragFactor)
+	}
+	if got := snapshot.RelevantFields(); !slices.Equal(got, snapshotFields) {
+		t.Errorf("unknown machine RelevantFields() = %v, want all fields", got)
+	}
+}
+
+func TestCalorieConversions(t *testing.T) {
+	// Reference points from the Concept2 calorie formula
+	if got := WattsToCaloriesPerHour(200); math.Abs(got-988.32) > 0.01 {
+		t.Errorf("WattsToCaloriesPerHour(200) = %.2f, want 988.32", got)
+	}
+	if got := PaceToCaloriesPerHour(120); math.Abs(got-997.08) > 0.01 {
+		t.Errorf("PaceToCaloriesPerHour(2:00) = %.2f, want 997.08", got)
+	}
+	if got := WattsToCaloriesPerHour(0); got != 0 {
+		t.Errorf("WattsToCaloriesPerHour(0) = %v, want 0", got)
+	}
+
+	for _, watts := range []float64{1, 50, 200, 455.5, 1000} {
+		if got := CaloriesPerHourToWatts(WattsToCaloriesPerHour(watts)); math.Abs(got-watts) > 1e-9 {
+			t.Errorf("round trip of %vW = %vW", watts, got)
+		}
+	}
+
+	// At or below the resting rate there is no power output
+	for _, cals := range []float64{-10, 0, 150, 300} {
+		if got := CaloriesPerHourToWatts(cals); got != 0 {
+			t.Errorf("CaloriesPerHourToWatts(%v) = %v, want 0", cals, got)
+		}
+		if got := CaloriesPerHourToPace(cals); got != 0 {
+			t.Errorf("CaloriesPerHourToPace(%v) = %v, want 0", cals, got)
+		}
+	}
+}
+
+func TestSnapshotRestDistance(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+		cmdData(csafe.PMCmdGetErgMachineType, byte(csafe.ErgMachineTypeStaticD))))
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetRestAvgHeartRate, 128),
+		cmdData(csafe.PMCmdGetLastRestDistance, 0x01, 0x2C)))
+
+	snapshot, err := p.GetWorkoutSnapshot()
+	if err != nil {
+		t.Fatalf("GetWorkoutSnapshot: %v", err)
+	}
+	if snapshot.RestDistance != 300 {
+		t.Errorf("RestDistance = %v, want 300", snapshot.RestDistance)
+	}
+
+	// The snapshot asks for the rest distance in its batch
+	var asked bool
+	for _, f := range decodeWritten(t, dev) {
+		asked = asked || bytes.Contains(f.Contents, []byte{csafe.PMCmdGetLastRestDistance})
+	}
+	if !asked {
+		t.Error("snapshot request does not include PMCmdGetLastRestDistance")
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded WorkoutSnapshot
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.RestDistance != 300 {
+		t.Errorf("RestDistance after JSON round trip = %v, want 300", decoded.RestDistance)
+	}
+	if !slices.Contains(snapshot.RelevantFields(), "rest_distance_m") {
+		t.Error("RelevantFields() is missing rest_distance_m")
+	}
+}
+
+func TestSnapshotDistanceMeters(t *testing.T) {
+	// 0x0000C351 is 50001 tenths of a meter: 5000.1 m
+	workDistance := cmdData(csafe.PMCmdGetWorkDistance, 0x00, 0x00, 0xC3, 0x51)
+
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+		cmdData(csafe.PMCmdGetErgMachineType, byte(csafe.ErgMachineTypeStaticD))))
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData, workDistance))
+
+	snapshot, err := p.GetWorkoutSnapshot()
+	if err != nil {
+		t.Fatalf("GetWorkoutSnapshot: %v", err)
+	}
+	if snapshot.Distance != 5000.1 {
+		t.Errorf("Distance = %v, want 5000.1", snapshot.Distance)
+	}
+
+	// The single-value getters agree with the snapshot
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData, workDistance))
+	tenths, err := p.GetPMWorkDistance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenths != 50001 {
+		t.Errorf("GetPMWorkDistance() = %d, want 50001", tenths)
+	}
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData, workDistance))
+	meters, err := p.GetPMWorkDistanceMeters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meters != snapshot.Distance {
+		t.Errorf("GetPMWorkDistanceMeters() = %v, want %v", meters, snapshot.Distance)
+	}
+}
+
+func TestSnapshotAvgStrokeRate(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+		cmdData(csafe.PMCmdGetErgMachineType, byte(csafe.ErgMachineTypeStaticD))))
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetStrokeRate, 26),
+		cmdData(csafe.PMCmdGetTotalAvgStrokeRate, 24)))
+
+	snapshot, err := p.GetWorkoutSnapshot()
+	if err != nil {
+		t.Fatalf("GetWorkoutSnapshot: %v", err)
+	}
+	if snapshot.StrokeRate != 26 || snapshot.AvgStrokeRate != 24 {
+		t.Errorf("stroke rate = %d avg %d, want 26 avg 24", snapshot.StrokeRate, snapshot.AvgStrokeRate)
+	}
+
+	// The whole batch still fits one frame at the default limit
+	frames := decodeWritten(t, dev)
+	if len(frames) != 2 {
+		t.Fatalf("sent %d frames, want the machine type lookup and one snapshot frame", len(frames))
+	}
+	if !bytes.Contains(frames[1].Contents, []byte{csafe.PMCmdGetTotalAvgStrokeRate}) {
+		t.Error("snapshot request does not include PMCmdGetTotalAvgStrokeRate")
+	}
+}
+
+func TestSnapshotSplitsForSmallFrames(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, cmdData(csafe.CmdGetCaps, 64, 64, 0))
+	if _, err := p.GetCapabilities(); err != nil {
+		t.Fatal(err)
+	}
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+		cmdData(csafe.PMCmdGetErgMachineType, byte(csafe.ErgMachineTypeStaticD))))
+
+	// Answer every snapshot command with a non-zero value of its response length
+	var responses [][]byte
+	for i, c := range snapshotCommands {
+		data := make([]byte, c.respLen)
+		data[len(data)-1] = byte(i + 1)
+		responses = append(responses, cmdData(c.cmd[0], data...))
+	}
+	// At a 64-byte limit the expected responses overflow one frame after
+	// command 10; the heart rate rides in the second frame
+	const split = 11
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData, responses[:split]...))
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData, responses[split:]...),
+		cmdData(csafe.CmdGetHRCur, 150))
+
+	snapshot, err := p.GetWorkoutSnapshot()
+	if err != nil {
+		t.Fatalf("GetWorkoutSnapshot: %v", err)
+	}
+
+	written := dev.GetWritten()
+	if len(written) != 4 {
+		t.Fatalf("sent %d frames, want caps, machine type and two snapshot frames", len(written))
+	}
+	for i, w := range written[2:] {
+		if len(w) > 64 {
+			t.Errorf("snapshot frame %d is %d bytes, limit 64", i, len(w))
+		}
+	}
+
+	// A field from each frame and the standard command are filled in
+	if snapshot.WorkoutType == "" || snapshot.Power == 0 || snapshot.DragFactor == 0 ||
+		snapshot.RestDistance == 0 || snapshot.HeartRate != 150 {
+		t.Errorf("snapshot not fully populated: %+v", snapshot)
+	}
+	if snapshot.AvgStrokeRate != 17 || snapshot.RestHeartRate != 19 {
+		t.Errorf("AvgStrokeRate %d RestHeartRate %d, want 17 and 19", snapshot.AvgStrokeRate, snapshot.RestHeartRate)
+	}
+}
+
+func TestDamperEstimate(t *testing.T) {
+	tests := []struct {
+		dragFactor byte
+		machine    csafe.ErgMachineType
+		want       int
+	}{
+		{0, csafe.ErgMachineTypeStaticD, 0},
+		{60, csafe.ErgMachineTypeStaticD, 1},
+		{95, csafe.ErgMachineTypeStaticD, 1},
+		{113, csafe.ErgMachineTypeStaticD, 3},
+		{136, csafe.ErgMachineTypeStaticD, 5},
+		{160, csafe.ErgMachineTypeStaticD, 7},
+		{180, csafe.ErgMachineTypeStaticD, 9},
+		{255, csafe.ErgMachineTypeStaticD, 10},
+		// The same drag factor maps to different settings on other machines
+		{175, csafe.ErgMachineTypeStaticD, 8},
+		{175, csafe.ErgMachineTypeStaticSki, 10},
+		{65, csafe.ErgMachineTypeBike, 1},
+	}
+
+	for _, tt := range tests {
+		if got := DamperEstimate(tt.dragFactor, tt.machine); got != tt.want {
+			t.Errorf("DamperEstimate(%d, %s) = %d, want %d", tt.dragFactor, tt.machine, got, tt.want)
+		}
+	}
+}
+
+func TestIsPRAgainst(t *testing.T) {
+	const thirtyMinutes = 180000 // Goal in 0.01s
+	mmss := func(m, s int) time.Duration { return time.Duration(m)*time.Minute + time.Duration(s)*time.Second }
+
+	history := []WorkoutSummary{
+		{WorkoutType: csafe.WorkoutTypeFixedDistSplits, Goal: 2000, Time: mmss(7, 5), Distance: 2000},
+		{WorkoutType: csafe.WorkoutTypeFixedDistNoSplits, Goal: 2000, Time: mmss(7, 10), Distance: 2000},
+		{WorkoutType: csafe.WorkoutTypeFixedDistSplits, Goal: 5000, Time: mmss(19, 0), Distance: 5000},
+		{WorkoutType: csafe.WorkoutTypeFixedTimeSplits, Goal: thirtyMinutes, Time: mmss(30, 0), Distance: 7500},
+		{WorkoutType: csafe.WorkoutTypeJustRowNoSplits, Time: mmss(45, 0), Distance: 20000},
+	}
+
+	tests := []struct {
+		name    string
+		s       WorkoutSummary
+		want    bool
+		because string
+	}{
+		{"2k PR", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedDistSplits, Goal: 2000, Time: mmss(7, 0)},
+			true, "new PR"},
+		{"2k behind the PR", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedDistSplits, Goal: 2000, Time: mmss(7, 8)},
+			false, "behind the PR of 7:05.00"},
+		{"no-split 2k against the split PR", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedDistNoSplits, Goal: 2000, Time: mmss(7, 7)},
+			false, "behind the PR of 7:05.00"},
+		{"no-split 5k against a split 5k", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedDistNoSplits, Goal: 5000, Time: mmss(18, 50)},
+			true, "new PR"},
+		{"30 minutes on distance", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedTimeNoSplits, Goal: thirtyMinutes, Time: mmss(30, 0), Distance: 7600},
+			true, "new PR: 7600.0m beats 7500.0m"},
+		{"30 minutes behind on distance", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedTimeSplits, Goal: thirtyMinutes, Time: mmss(30, 0), Distance: 7400},
+			false, "behind the PR of 7500.0m"},
+		{"calorie goal is not a distance goal", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedCalorieSplits, Goal: 2000, Time: mmss(6, 0)},
+			false, "no comparable workouts"},
+		{"new distance", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedDistSplits, Goal: 1000, Time: mmss(3, 20)},
+			false, "no comparable workouts"},
+		{"just row", WorkoutSummary{WorkoutType: csafe.WorkoutTypeJustRowNoSplits, Time: mmss(50, 0), Distance: 25000},
+			false, "not compared"},
+		{"intervals", WorkoutSummary{WorkoutType: csafe.WorkoutTypeFixedDistInterval, Goal: 500, Time: mmss(1, 30)},
+			false, "not compared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, why := tt.s.IsPRAgainst(history)
+			if got != tt.want {
+				t.Errorf("IsPRAgainst() = %v (%s), want %v", got, why, tt.want)
+			}
+			if !strings.Contains(why, tt.because) {
+				t.Errorf("IsPRAgainst() reason = %q, want it to mention %q", why, tt.because)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package device
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultDialTimeout bounds connecting to a networked PM5
+const DefaultDialTimeout = 5 * time.Second
+
+// netHeaderSize is the length prefix on every message: a big-endian uint16
+// byte count followed by one HID report payload, without the report ID
+const netHeaderSize = 2
+
+// NetDevice is an HID device reached over a network connection, for a PM5
+// attached to another machine that forwards its reports
+// Each Write sends one length-prefixed report and each Read returns one,
+// however the stream happens to be split into packets.
+type NetDevice struct {
+	addr         string
+	conn         net.Conn
+	mu           sync.Mutex
+	isOpen       bool
+	pending      []byte
+	writeTimeout time.Duration
+}
+
+// NewNetDevice creates a device that connects to addr (host:port) when opened
+func NewNetDevice(addr string) *NetDevice {
+	return &NetDevice{
+		addr:         addr,
+		writeTimeout: DefaultWriteTimeout,
+	}
+}
+
+// DialNet connects to a PM5 served at addr and returns the open device
+func DialNet(addr string) (HIDDevice, error) {
+	d := NewNetDevice(addr)
+	if err := d.Open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Open connects to the remote PM5
+func (d *NetDevice) Open() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.isOpen {
+		return ErrDeviceAlreadyOpen
+	}
+	return d.dial()
+}
+
+func (d *NetDevice) dial() error {
+	conn, err := net.DialTimeout("tcp", d.addr, DefaultDialTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOpenFailed, err)
+	}
+	d.conn = conn
+	d.pending = nil
+	d.isOpen = true
+	return nil
+}
+
+// Reconnect drops the current connection and dials the same address again
+func (d *NetDevice) Reconnect() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn != nil {
+		// The connection is likely dead; a close error is expected and not useful
+		d.conn.Close()
+		d.conn = nil
+	}
+	d.isOpen = false
+	return d.dial()
+}
+
+// Close closes the network connection
+func (d *NetDevice) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.isOpen {
+		return nil
+	}
+	d.isOpen = false
+	d.pending = nil
+	if err := d.conn.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCloseFailed, err)
+	}
+	return nil
+}
+
+// Write sends data as a single length-prefixed report
+func (d *NetDevice) Write(data []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.isOpen {
+		return 0, ErrDeviceNotOpen
+	}
+	if len(data) > 0xFFFF {
+		return 0, fmt.Errorf("%w: %d byte report", ErrWriteFailed, len(data))
+	}
+
+	msg := make([]byte, netHeaderSize+len(data))
+	binary.BigEndian.PutUint16(msg, uint16(len(data)))
+	copy(msg[netHeaderSize:], data)
+
+	d.conn.SetWriteDeadline(time.Now().Add(d.writeTimeout))
+	if _, err := d.conn.Write(msg); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrWriteFailed, err)
+	}
+	return len(data), nil
+}
+
+// Read returns the next complete report, waiting up to timeout for it
+// Bytes of a partly received report are kept for the next Read on timeout.
+func (d *NetDevice) Read(timeout time.Duration) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.isOpen {
+		return nil, ErrDeviceNotOpen
+	}
+
+	d.conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, ReportID2Size)
+	for {
+		if report, ok := d.nextReport(); ok {
+			return report, nil
+		}
+		n, err := d.conn.Read(buf)
+		d.pending = append(d.pending, buf[:n]...)
+		if err == nil {
+			continue
+		}
+		if report, ok := d.nextReport(); ok {
+			return report, nil
+		}
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			return nil, fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrReadFailed, err)
+	}
+}
+
+// nextReport removes and returns the first whole report in the pending bytes
+func (d *NetDevice) nextReport() ([]byte, bool) {
+	if len(d.pending) < netHeaderSize {
+		return nil, false
+	}
+	n := int(binary.BigEndian.Uint16(d.pending))
+	if len(d.pending) < netHeaderSize+n {
+		return nil, false
+	}
+	report := make([]byte, n)
+	copy(report, d.pending[netHeaderSize:])
+	d.pending = d.pending[netHeaderSize+n:]
+	return report, true
+}
+
+// IsOpen returns whether the connection is open
+func (d *NetDevice) IsOpen() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.isOpen
+}
+
+// GetInfo describes the remote device by its address
+func (d *NetDevice) GetInfo() DeviceInfo {
+	return DeviceInfo{
+		VendorID:  PM5VendorID,
+		ProductID: PM5ProductID,
+		Product:   "PM5 (network)",
+		Path:      d.addr,
+		Model:     ModelPM5,
+	}
+}
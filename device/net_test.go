@@ -0,0 +1,194 @@
+package device
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+// netPeer starts a loopback listener and returns a NetDevice dialled to it
+// together with the server end of the connection
+func netPeer(t *testing.T) (*NetDevice, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+
+	dev, err := DialNet(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dev.Close() })
+
+	server, ok := <-accepted
+	if !ok {
+		t.Fatal("accept failed")
+	}
+	t.Cleanup(func() { server.Close() })
+	return dev.(*NetDevice), server
+}
+
+// netMessage length-prefixes a report the way NetDevice frames it
+func netMessage(report []byte) []byte {
+	msg := make([]byte, netHeaderSize, netHeaderSize+len(report))
+	binary.BigEndian.PutUint16(msg, uint16(len(report)))
+	return append(msg, report...)
+}
+
+func TestNetDeviceWriteFraming(t *testing.T) {
+	dev, server := netPeer(t)
+	report := []byte{0xF1, 0x80, 0x80, 0xF2}
+
+	if n, err := dev.Write(report); err != nil || n != len(report) {
+		t.Fatalf("Write() = %d, %v", n, err)
+	}
+
+	got := make([]byte, netHeaderSize+len(report))
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(server, got); err != nil {
+		t.Fatal(err)
+	}
+	if want := netMessage(report); !bytes.Equal(got, want) {
+		t.Errorf("wire bytes = % X, want % X", got, want)
+	}
+}
+
+func TestNetDeviceReadReassembles(t *testing.T) {
+	dev, server := netPeer(t)
+
+	long := make([]byte, ReportID2Size)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	short := []byte{0xF1, 0x01, 0x01, 0xF2}
+	stream := append(netMessage(long), netMessage(short)...)
+
+	// Send the stream a few bytes at a time so each report spans many reads
+	go func() {
+		for i := 0; i < len(stream); i += 7 {
+			server.Write(stream[i:min(i+7, len(stream))])
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	for _, want := range [][]byte{long, short} {
+		got, err := dev.Read(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Read() = %d bytes % X, want %d bytes", len(got), got, len(want))
+		}
+	}
+}
+
+func TestNetDeviceReadKeepsPartialReport(t *testing.T) {
+	dev, server := netPeer(t)
+	report := []byte{0xF1, 0x01, 0x80, 0x00, 0x80, 0xF2}
+	msg := netMessage(report)
+
+	server.Write(msg[:3])
+	if _, err := dev.Read(20 * time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Read() error = %v, want ErrTimeout", err)
+	}
+
+	server.Write(msg[3:])
+	got, err := dev.Read(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, report) {
+		t.Errorf("Read() = % X, want % X", got, report)
+	}
+}
+
+func TestNetDeviceGetStatus(t *testing.T) {
+	dev, server := netPeer(t)
+
+	// The remote end answers a GetStatus request with a Ready status
+	go func() {
+		header := make([]byte, netHeaderSize)
+		if _, err := io.ReadFull(server, header); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint16(header))
+		if _, err := io.ReadFull(server, request); err != nil {
+			return
+		}
+		frame, err := csafe.DecodeFrame(request)
+		if err != nil || !bytes.Equal(frame.Contents, []byte{csafe.CmdGetStatus}) {
+			return
+		}
+		resp, _ := csafe.EncodeFrame(&csafe.Frame{Contents: []byte{0x01}})
+		server.Write(netMessage(resp))
+	}()
+
+	request, err := csafe.EncodeFrame(&csafe.Frame{Contents: []byte{csafe.CmdGetStatus}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.Write(request); err != nil {
+		t.Fatal(err)
+	}
+	data, err := dev.Read(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := csafe.DecodeFrame(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp.Contents, []byte{0x01}) {
+		t.Errorf("status response = % X, want 01", resp.Contents)
+	}
+}
+
+func TestNetDeviceNotOpen(t *testing.T) {
+	dev := NewNetDevice("127.0.0.1:0")
+	if _, err := dev.Write([]byte{0xF1}); !errors.Is(err, ErrDeviceNotOpen) {
+		t.Errorf("Write() error = %v, want ErrDeviceNotOpen", err)
+	}
+	if _, err := dev.Read(time.Millisecond); !errors.Is(err, ErrDeviceNotOpen) {
+		t.Errorf("Read() error = %v, want ErrDeviceNotOpen", err)
+	}
+}
+
+func TestNetDeviceErrors(t *testing.T) {
+	// Nothing listens on a port whose listener has been closed
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	if _, err := DialNet(addr); !errors.Is(err, ErrOpenFailed) {
+		t.Errorf("DialNet() error = %v, want ErrOpenFailed", err)
+	}
+
+	dev, server := netPeer(t)
+	if err := dev.Open(); !errors.Is(err, ErrDeviceAlreadyOpen) {
+		t.Errorf("Open() error = %v, want ErrDeviceAlreadyOpen", err)
+	}
+	server.Close()
+	if _, err := dev.Read(time.Second); !errors.Is(err, ErrReadFailed) {
+		t.Errorf("Read() after the peer closed error = %v, want ErrReadFailed", err)
+	}
+}
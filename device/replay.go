@@ -0,0 +1,103 @@
+package device
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayDevice is a mock HID device that answers each written frame with a
+// captured response, keyed by the exact bytes written
+// Unlike MockDevice it does not depend on the order commands are issued, so a
+// single corpus of captures can drive any sequence of getters.
+type ReplayDevice struct {
+	info     DeviceInfo
+	isOpen   bool
+	captures map[string][]byte
+	pending  [][]byte
+	misses   [][]byte
+	mu       sync.Mutex
+}
+
+// NewReplayDevice creates a new replay device for testing
+func NewReplayDevice() *ReplayDevice {
+	return &ReplayDevice{
+		info:     NewMockDevice().GetInfo(),
+		captures: make(map[string][]byte),
+	}
+}
+
+// AddCapture registers the response returned when request is written
+func (r *ReplayDevice) AddCapture(request, response []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := make([]byte, len(response))
+	copy(copied, response)
+	r.captures[string(request)] = copied
+}
+
+// Misses returns all written frames that had no matching capture
+func (r *ReplayDevice) Misses() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([][]byte, len(r.misses))
+	copy(result, r.misses)
+	return result
+}
+
+func (r *ReplayDevice) Open() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isOpen {
+		return ErrDeviceAlreadyOpen
+	}
+	r.isOpen = true
+	return nil
+}
+
+func (r *ReplayDevice) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.isOpen = false
+	r.pending = nil
+	return nil
+}
+
+func (r *ReplayDevice) Write(data []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.isOpen {
+		return 0, ErrDeviceNotOpen
+	}
+	if resp, ok := r.captures[string(data)]; ok {
+		r.pending = append(r.pending, resp)
+	} else {
+		copied := make([]byte, len(data))
+		copy(copied, data)
+		r.misses = append(r.misses, copied)
+	}
+	return len(data), nil
+}
+
+func (r *ReplayDevice) Read(timeout time.Duration) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.isOpen {
+		return nil, ErrDeviceNotOpen
+	}
+	if len(r.pending) == 0 {
+		return nil, ErrTimeout
+	}
+	data := r.pending[0]
+	r.pending = r.pending[1:]
+	return data, nil
+}
+
+func (r *ReplayDevice) IsOpen() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isOpen
+}
+
+func (r *ReplayDevice) GetInfo() DeviceInfo {
+	return r.info
+}
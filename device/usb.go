@@ -2,8 +2,10 @@
 package device
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +22,8 @@ func init() {
 // USB constants for PM5
 const (
 	PM5VendorID  uint16 = 0x17A4 // Concept2 Vendor ID
+	PM3ProductID uint16 = 0x0001 // PM3 Product ID
+	PM4ProductID uint16 = 0x0002 // PM4 Product ID
 	PM5ProductID uint16 = 0x0046 // PM5 Product ID (may vary)
 
 	// HID Report IDs
@@ -35,11 +39,109 @@ var (
 	ErrDeviceNotFound    = errors.New("PM5 device not found")
 	ErrDeviceNotOpen     = errors.New("device not open")
 	ErrDeviceAlreadyOpen = errors.New("device already open")
+	ErrOpenFailed        = errors.New("open failed")
+	ErrCloseFailed       = errors.New("close failed")
 	ErrWriteFailed       = errors.New("write failed")
 	ErrReadFailed        = errors.New("read failed")
 	ErrTimeout           = errors.New("operation timed out")
+	ErrDeviceBusy        = errors.New("device busy")
 )
 
+// pmProducts is the allow-list of Concept2 performance monitor product IDs,
+// which all share the PM5VendorID
+var pmProducts = [...]struct {
+	productID uint16
+	model     Model
+}{
+	{PM3ProductID, ModelPM3},
+	{PM4ProductID, ModelPM4},
+	{PM5ProductID, ModelPM5},
+}
+
+// PMProductIDs returns the product IDs of Concept2 performance monitors
+func PMProductIDs() []uint16 {
+	ids := make([]uint16, len(pmProducts))
+	for i, p := range pmProducts {
+		ids[i] = p.productID
+	}
+	return ids
+}
+
+// isPMProductID reports whether productID is on the performance monitor allow-list
+func isPMProductID(productID uint16) bool {
+	for _, p := range pmProducts {
+		if p.productID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// Model is the performance monitor generation detected for a device
+type Model int
+
+const (
+	ModelUnknown Model = iota
+	ModelPM3
+	ModelPM4
+	ModelPM5
+)
+
+func (m Model) String() string {
+	switch m {
+	case ModelPM3:
+		return "PM3"
+	case ModelPM4:
+		return "PM4"
+	case ModelPM5:
+		return "PM5"
+	default:
+		return "Unknown"
+	}
+}
+
+// detectModel identifies a monitor by product ID, falling back to the product
+// string since some PM5 firmware reports a different product ID
+func detectModel(productID uint16, product string) Model {
+	for _, p := range pmProducts {
+		if p.productID == productID {
+			return p.model
+		}
+	}
+
+	product = strings.ToUpper(product)
+	switch {
+	case strings.Contains(product, "PM5"):
+		return ModelPM5
+	case strings.Contains(product, "PM4"):
+		return ModelPM4
+	case strings.Contains(product, "PM3"):
+		return ModelPM3
+	}
+	return ModelUnknown
+}
+
+// hidOpen, hidOpenPath and hidEnumerate wrap the HID library
+// They are variables so that tests can substitute blocking or failing implementations
+var (
+	hidOpen      = hid.Open
+	hidOpenPath  = hid.OpenPath
+	hidEnumerate = hid.Enumerate
+)
+
+// hidHandle is the part of *hid.Device used by USBDevice, so that tests can
+// substitute a handle that fails
+type hidHandle interface {
+	Write(p []byte) (int, error)
+	ReadWithTimeout(p []byte, timeout time.Duration) (int, error)
+	Close() error
+}
+
+// Reconnecter is implemented by devices that can recover a lost connection
+type Reconnecter interface {
+	Reconnect() error
+}
+
 // HIDDevice is an interface for HID device operations
 // This allows for different implementations (real USB, mock for testing)
 type HIDDevice interface {
@@ -59,6 +161,7 @@ type DeviceInfo struct {
 	Product      string
 	Manufacturer string
 	Path         string
+	Model        Model // Detected from the product ID or product string
 }
 
 // USBDevice represents a real USB HID device connection
@@ -70,8 +173,7 @@ type USBDevice struct {
 	readTimeout  time.Duration
 	writeTimeout time.Duration
 
-	// These would be replaced with actual HID device handle
-	device *hid.Device
+	device hidHandle
 }
 
 // NewUSBDevice creates a new USB device instance
@@ -92,16 +194,72 @@ func (d *USBDevice) Open() error {
 		return ErrDeviceAlreadyOpen
 	}
 
-	// Open the first PM5 device found
+	// Open the device described by info, so that with several ergs attached
+	// each USBDevice addresses its own machine
+	dev, err := d.openHandle(false)
+	if err != nil {
+		return err
+	}
+
+	d.device = dev
+	d.isOpen = true
+	return nil
+}
+
+// openHandle opens the HID device identified by d.info
+// The path pins the exact device found by enumeration, while the serial number
+// survives re-plugging, so preferSerial is set when reconnecting. Without
+// either, the first recognized PM of any model is opened.
+func (d *USBDevice) openHandle(preferSerial bool) (hidHandle, error) {
+	productID := d.info.ProductID
+	if productID == 0 {
+		productID = PM5ProductID
+	}
+
+	var dev *hid.Device
 	var err error
-	d.device, err = hid.OpenFirst(PM5VendorID, PM5ProductID)
+	switch {
+	case d.info.SerialNumber != "" && (preferSerial || d.info.Path == ""):
+		dev, err = hidOpen(PM5VendorID, productID, d.info.SerialNumber)
+	case d.info.Path != "":
+		dev, err = hidOpenPath(d.info.Path)
+	default:
+		info, findErr := firstPM()
+		if findErr != nil {
+			return nil, findErr
+		}
+		dev, err = hidOpenPath(info.Path)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to open device: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrOpenFailed, err)
 	}
-	if d.device == nil {
-		return ErrDeviceNotFound
+	if dev == nil {
+		return nil, ErrDeviceNotFound
 	}
+	return dev, nil
+}
+
+// Reconnect closes the current handle, which may be stale after a USB dropout,
+// and re-opens the same PM5
+// The device is found by serial number, which survives re-plugging, falling back
+// to the stored path and then to the first PM5 found.
+func (d *USBDevice) Reconnect() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.device != nil {
+		// The handle is likely dead; a close error is expected and not useful
+		d.device.Close()
+		d.device = nil
+	}
+	d.isOpen = false
 
+	dev, err := d.openHandle(true)
+	if err != nil {
+		return err
+	}
+
+	d.device = dev
 	d.isOpen = true
 	return nil
 }
@@ -117,7 +275,7 @@ func (d *USBDevice) Close() error {
 
 	if d.device != nil {
 		if err := d.device.Close(); err != nil {
-			return fmt.Errorf("failed to close device: %w", err)
+			return fmt.Errorf("%w: %w", ErrCloseFailed, err)
 		}
 	}
 
@@ -135,21 +293,25 @@ func (d *USBDevice) Write(data []byte) (int, error) {
 	}
 
 	// Prepare HID report
-	// Report ID 2 supports up to 120 bytes
-	report := make([]byte, ReportID2Size)
-	report[0] = 0x02 // Report ID 2
+	// Short frames use the 20-byte Report ID 1 to save bus time; anything
+	// larger uses Report ID 2, which supports up to 120 bytes
+	var report []byte
+	if len(data) <= ReportID1Size-1 {
+		report = make([]byte, ReportID1Size)
+		report[0] = 0x01 // Report ID 1
+	} else {
+		report = make([]byte, ReportID2Size)
+		report[0] = 0x02 // Report ID 2
+	}
 
 	// Copy data into report
-	n := len(data)
-	if n > 120 {
-		n = 120
-	}
+	n := min(len(data), len(report)-1)
 	copy(report[1:], data[:n])
 
 	// Write to device
 	written, err := d.device.Write(report)
 	if err != nil {
-		return 0, fmt.Errorf("HID write failed: %w", err)
+		return 0, fmt.Errorf("%w: %w", ErrWriteFailed, err)
 	}
 
 	// Return the number of actual data bytes written (excluding report ID)
@@ -173,14 +335,26 @@ func (d *USBDevice) Read(timeout time.Duration) ([]byte, error) {
 
 	// Read from device with timeout
 	n, err := d.device.ReadWithTimeout(buf, timeout)
+	if errors.Is(err, hid.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("HID read failed: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrReadFailed, err)
 	}
 
 	if n == 0 {
 		return nil, ErrTimeout
 	}
 
+	// The PM may answer on either report ID; never return more than that
+	// report's payload
+	switch buf[0] {
+	case 0x01:
+		n = min(n, ReportID1Size)
+	case 0x02:
+		n = min(n, ReportID2Size)
+	}
+
 	// Strip report ID from response
 	if n > 1 {
 		return buf[1:n], nil
@@ -215,12 +389,76 @@ func (d *USBDevice) SetWriteTimeout(timeout time.Duration) {
 	d.writeTimeout = timeout
 }
 
-// EnumerateDevices returns a list of connected PM5 devices
-func EnumerateDevices() ([]DeviceInfo, error) {
+// firstPM returns the first attached performance monitor, preferring a device
+// on the product ID allow-list over one recognized only by its product string
+// Other Concept2 devices are never chosen; ErrDeviceNotFound is returned if no
+// monitor is attached.
+func firstPM() (DeviceInfo, error) {
+	devices, err := EnumerateDevices(KnownModel())
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	if len(devices) == 0 {
+		return DeviceInfo{}, ErrDeviceNotFound
+	}
+	for _, info := range devices {
+		if isPMProductID(info.ProductID) {
+			return info, nil
+		}
+	}
+	return devices[0], nil
+}
+
+// DeviceFilter selects devices during enumeration
+type DeviceFilter func(DeviceInfo) bool
+
+// KnownModel returns a filter matching devices recognized as a PM3, PM4 or PM5,
+// either by a product ID on the allow-list (see PMProductIDs) or by product string
+func KnownModel() DeviceFilter {
+	return func(info DeviceInfo) bool {
+		return info.Model != ModelUnknown
+	}
+}
+
+// ProductSubstring returns a filter matching devices whose product string
+// contains substr, ignoring case
+func ProductSubstring(substr string) DeviceFilter {
+	substr = strings.ToLower(substr)
+	return func(info DeviceInfo) bool {
+		return strings.Contains(strings.ToLower(info.Product), substr)
+	}
+}
+
+// FilterByProductSubstring returns the devices whose product string contains
+// substr, ignoring case
+func FilterByProductSubstring(devices []DeviceInfo, substr string) []DeviceInfo {
+	return filterDevices(devices, ProductSubstring(substr))
+}
+
+// filterDevices returns the devices accepted by every filter
+func filterDevices(devices []DeviceInfo, filters ...DeviceFilter) []DeviceInfo {
+	result := make([]DeviceInfo, 0, len(devices))
+outer:
+	for _, info := range devices {
+		for _, filter := range filters {
+			if !filter(info) {
+				continue outer
+			}
+		}
+		result = append(result, info)
+	}
+	return result
+}
+
+// EnumerateDevices returns a list of connected Concept2 devices of any product
+// ID, each tagged with its detected Model
+// Only devices accepted by every filter are returned, e.g.
+// EnumerateDevices(KnownModel()) or EnumerateDevices(ProductSubstring("PM5"))
+func EnumerateDevices(filters ...DeviceFilter) ([]DeviceInfo, error) {
 	result := make([]DeviceInfo, 0)
 
 	// Enumerate with a callback function to collect device info
-	err := hid.Enumerate(PM5VendorID, 0, func(info *hid.DeviceInfo) error {
+	err := hidEnumerate(PM5VendorID, 0, func(info *hid.DeviceInfo) error {
 		result = append(result, DeviceInfo{
 			VendorID:     info.VendorID,
 			ProductID:    info.ProductID,
@@ -228,6 +466,7 @@ func EnumerateDevices() ([]DeviceInfo, error) {
 			Product:      info.ProductStr,
 			Manufacturer: info.MfrStr,
 			Path:         info.Path,
+			Model:        detectModel(info.ProductID, info.ProductStr),
 		})
 		return nil
 	})
@@ -236,21 +475,205 @@ func EnumerateDevices() ([]DeviceInfo, error) {
 		return nil, fmt.Errorf("failed to enumerate HID devices: %w", err)
 	}
 
+	if len(filters) > 0 {
+		result = filterDevices(result, filters...)
+	}
 	return result, nil
 }
 
-// FindFirstPM5 finds and returns the first available PM5 device
+// EnumerateDevicesContext is like EnumerateDevices but returns ctx.Err() if ctx
+// is done before enumeration completes
+// This keeps startup from hanging when the HID subsystem is wedged; the abandoned
+// enumeration is left to finish in the background.
+func EnumerateDevicesContext(ctx context.Context, filters ...DeviceFilter) ([]DeviceInfo, error) {
+	type result struct {
+		devices []DeviceInfo
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		devices, err := EnumerateDevices(filters...)
+		done <- result{devices, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.devices, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// watchInterval is how often WatchDevices re-enumerates; tests shorten it
+var watchInterval = time.Second
+
+// DeviceEventType is the kind of change reported by WatchDevices
+type DeviceEventType int
+
+const (
+	DeviceAdded DeviceEventType = iota
+	DeviceRemoved
+)
+
+func (t DeviceEventType) String() string {
+	if t == DeviceAdded {
+		return "Added"
+	}
+	return "Removed"
+}
+
+// DeviceEvent reports a PM5 being plugged in or removed
+type DeviceEvent struct {
+	Type DeviceEventType
+	Info DeviceInfo
+}
+
+// deviceKey identifies a device across enumerations
+func deviceKey(info DeviceInfo) string {
+	if info.Path != "" {
+		return info.Path
+	}
+	return info.SerialNumber
+}
+
+// WatchDevices enumerates every second and reports devices that were
+// added or removed since the previous enumeration
+// Devices already attached are reported as added first. An enumeration that
+// fails after the initial one is skipped. The channel is closed when ctx is done.
+func WatchDevices(ctx context.Context) (<-chan DeviceEvent, error) {
+	devices, err := EnumerateDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan DeviceEvent, len(devices))
+	known := make(map[string]DeviceInfo, len(devices))
+	for _, info := range devices {
+		known[deviceKey(info)] = info
+		events <- DeviceEvent{Type: DeviceAdded, Info: info}
+	}
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		send := func(ev DeviceEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			devices, err := EnumerateDevicesContext(ctx)
+			if err != nil {
+				continue
+			}
+
+			current := make(map[string]DeviceInfo, len(devices))
+			for _, info := range devices {
+				key := deviceKey(info)
+				current[key] = info
+				if _, ok := known[key]; !ok {
+					if !send(DeviceEvent{Type: DeviceAdded, Info: info}) {
+						return
+					}
+				}
+			}
+			for key, info := range known {
+				if _, ok := current[key]; !ok {
+					if !send(DeviceEvent{Type: DeviceRemoved, Info: info}) {
+						return
+					}
+				}
+			}
+			known = current
+		}
+	}()
+
+	return events, nil
+}
+
+// FindFirstPM5 finds and returns the first available performance monitor
+// Only devices recognized as a PM3, PM4 or PM5 are considered; other Concept2
+// devices are skipped and ErrDeviceNotFound is returned if no monitor is attached.
 func FindFirstPM5() (*USBDevice, error) {
+	info, err := firstPM()
+	if err != nil {
+		return nil, err
+	}
+	return NewUSBDevice(info), nil
+}
+
+// OpenBySerial finds the PM5 with the given serial number and opens it
+// Returns ErrDeviceNotFound if no attached PM5 has that serial number
+func OpenBySerial(serial string) (*USBDevice, error) {
 	devices, err := EnumerateDevices()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(devices) == 0 {
-		return nil, ErrDeviceNotFound
+	for _, info := range devices {
+		if info.SerialNumber == serial {
+			dev := NewUSBDevice(info)
+			if err := dev.Open(); err != nil {
+				return nil, err
+			}
+			return dev, nil
+		}
+	}
+
+	return nil, ErrDeviceNotFound
+}
+
+// FindFirstPM5WithTimeout finds the first available PM5 and opens it
+// Returns ErrDeviceBusy if the open does not complete within timeout, which
+// usually means another process still holds the device
+func FindFirstPM5WithTimeout(timeout time.Duration) (*USBDevice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return FindFirstPM5Context(ctx)
+}
+
+// FindFirstPM5Context finds the first available PM5 and opens it
+// Returns ErrDeviceBusy if ctx is done before the open completes
+func FindFirstPM5Context(ctx context.Context) (*USBDevice, error) {
+	dev, err := FindFirstPM5()
+	if err != nil {
+		return nil, err
 	}
 
-	return NewUSBDevice(devices[0]), nil
+	done := make(chan error, 1)
+	go func() {
+		done <- dev.Open()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+		return dev, nil
+	case <-ctx.Done():
+		// Release the device if the abandoned open eventually succeeds
+		go func() {
+			if err := <-done; err == nil {
+				dev.Close()
+			}
+		}()
+		return nil, fmt.Errorf("%w: %w", ErrDeviceBusy, ctx.Err())
+	}
 }
 
 // String returns a string representation of the device info
@@ -279,6 +702,7 @@ func NewMockDevice() *MockDevice {
 			SerialNumber: "430000000",
 			Product:      "Concept2 Performance Monitor 5 (PM5)",
 			Manufacturer: "Concept2",
+			Model:        ModelPM5,
 		},
 		responses: make([][]byte, 0),
 		written:   make([][]byte, 0),
@@ -0,0 +1,419 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sstallion/go-hid"
+)
+
+// fakeHID replaces the HID library seams for the duration of a test
+// Enumeration reports devices, and opening blocks until release is closed and
+// then fails, as when another process holds the device. Each open signals
+// opened as it returns, so a test can wait out an abandoned open.
+func fakeHID(t *testing.T, devices []hid.DeviceInfo) (release chan struct{}, opened chan struct{}) {
+	t.Helper()
+	origOpen, origOpenPath, origEnumerate := hidOpen, hidOpenPath, hidEnumerate
+	t.Cleanup(func() {
+		hidOpen, hidOpenPath, hidEnumerate = origOpen, origOpenPath, origEnumerate
+	})
+
+	release = make(chan struct{})
+	opened = make(chan struct{}, 8)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	hidEnumerate = func(vid, pid uint16, fn hid.EnumFunc) error {
+		for i := range devices {
+			if err := fn(&devices[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	hidOpenPath = func(path string) (*hid.Device, error) {
+		<-release
+		opened <- struct{}{}
+		return nil, errors.New("device claimed")
+	}
+	hidOpen = func(vid, pid uint16, serial string) (*hid.Device, error) {
+		<-release
+		opened <- struct{}{}
+		return nil, errors.New("device claimed")
+	}
+	return release, opened
+}
+
+var fakePM5 = hid.DeviceInfo{
+	Path:       "/dev/hidraw0",
+	VendorID:   PM5VendorID,
+	ProductID:  PM5ProductID,
+	SerialNbr:  "430000001",
+	ProductStr: "Concept2 Performance Monitor 5 (PM5)",
+}
+
+func TestFindFirstPM5WithTimeoutBusy(t *testing.T) {
+	release, opened := fakeHID(t, []hid.DeviceInfo{fakePM5})
+
+	start := time.Now()
+	dev, err := FindFirstPM5WithTimeout(20 * time.Millisecond)
+	if !errors.Is(err, ErrDeviceBusy) {
+		t.Fatalf("FindFirstPM5WithTimeout() error = %v, want ErrDeviceBusy", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %v does not wrap context.DeadlineExceeded", err)
+	}
+	if dev != nil {
+		t.Errorf("FindFirstPM5WithTimeout() returned a device on timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %v, want about the timeout", elapsed)
+	}
+
+	close(release)
+	<-opened
+
+	// A failed open that finishes first is returned as is
+	if _, err := FindFirstPM5WithTimeout(time.Second); !errors.Is(err, ErrOpenFailed) {
+		t.Errorf("FindFirstPM5WithTimeout() error = %v, want ErrOpenFailed", err)
+	}
+}
+
+func TestFindFirstPM5WithTimeoutNoDevice(t *testing.T) {
+	fakeHID(t, nil)
+	if _, err := FindFirstPM5WithTimeout(time.Second); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("FindFirstPM5WithTimeout() error = %v, want ErrDeviceNotFound", err)
+	}
+}
+
+func TestEnumerateDevicesContextCancelled(t *testing.T) {
+	release, _ := fakeHID(t, nil)
+	finished := make(chan struct{})
+	hidEnumerate = func(vid, pid uint16, fn hid.EnumFunc) error {
+		defer close(finished)
+		<-release
+		return fn(&fakePM5)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	devices, err := EnumerateDevicesContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("EnumerateDevicesContext() error = %v, want context.Canceled", err)
+	}
+	if devices != nil {
+		t.Errorf("EnumerateDevicesContext() = %v, want nil", devices)
+	}
+
+	close(release)
+	<-finished
+}
+
+func TestEnumerateDevicesContextCompletes(t *testing.T) {
+	fakeHID(t, []hid.DeviceInfo{fakePM5})
+
+	devices, err := EnumerateDevicesContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].Path != fakePM5.Path || devices[0].Model != ModelPM5 {
+		t.Errorf("EnumerateDevicesContext() = %+v, want the fake PM5", devices)
+	}
+}
+
+// fakeBus is a mixed set of Concept2 devices with inconsistent product strings
+var fakeBus = []hid.DeviceInfo{
+	{Path: "a", VendorID: PM5VendorID, ProductID: PM5ProductID, ProductStr: "Concept2 Performance Monitor 5 (PM5)"},
+	{Path: "b", VendorID: PM5VendorID, ProductID: 0x0080, ProductStr: "concept2 pm5"},
+	{Path: "c", VendorID: PM5VendorID, ProductID: PM3ProductID, ProductStr: "Performance Monitor 3"},
+	{Path: "d", VendorID: PM5VendorID, ProductID: 0x0099, ProductStr: "Concept2 Logcard Reader"},
+}
+
+func devicePaths(devices []DeviceInfo) []string {
+	paths := make([]string, 0, len(devices))
+	for _, d := range devices {
+		paths = append(paths, d.Path)
+	}
+	return paths
+}
+
+func TestFindFirstPM5SkipsOtherDevices(t *testing.T) {
+	logcard, stringOnly, pm3 := fakeBus[3], fakeBus[1], fakeBus[2]
+
+	tests := []struct {
+		name    string
+		devices []hid.DeviceInfo
+		want    string
+	}{
+		{"allow-listed ID preferred", []hid.DeviceInfo{logcard, stringOnly, pm3}, "c"},
+		{"product string fallback", []hid.DeviceInfo{logcard, stringOnly}, "b"},
+		{"no monitor", []hid.DeviceInfo{logcard}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHID(t, tt.devices)
+			dev, err := FindFirstPM5()
+			if tt.want == "" {
+				if !errors.Is(err, ErrDeviceNotFound) {
+					t.Errorf("FindFirstPM5() error = %v, want ErrDeviceNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dev.GetInfo().Path; got != tt.want {
+				t.Errorf("FindFirstPM5() chose %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPMProductIDsIsACopy(t *testing.T) {
+	ids := PMProductIDs()
+	if !slices.Equal(ids, []uint16{PM3ProductID, PM4ProductID, PM5ProductID}) {
+		t.Errorf("PMProductIDs() = %#04x", ids)
+	}
+	ids[0] = 0x0099
+	if detectModel(0x0099, "") != ModelUnknown || detectModel(PM3ProductID, "") != ModelPM3 {
+		t.Error("changing the returned slice altered the allow-list")
+	}
+}
+
+func TestEnumerateDevicesFilters(t *testing.T) {
+	fakeHID(t, fakeBus)
+
+	tests := []struct {
+		name    string
+		filters []DeviceFilter
+		want    []string
+	}{
+		{"no filter", nil, []string{"a", "b", "c", "d"}},
+		{"PM5 substring ignores case", []DeviceFilter{ProductSubstring("PM5")}, []string{"a", "b"}},
+		{"Performance Monitor", []DeviceFilter{ProductSubstring("Performance Monitor")}, []string{"a", "c"}},
+		{"known model", []DeviceFilter{KnownModel()}, []string{"a", "b", "c"}},
+		{"all filters must match", []DeviceFilter{KnownModel(), ProductSubstring("monitor")}, []string{"a", "c"}},
+		{"no match", []DeviceFilter{ProductSubstring("PM4")}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devices, err := EnumerateDevices(tt.filters...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := devicePaths(devices); !slices.Equal(got, tt.want) {
+				t.Errorf("EnumerateDevices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterByProductSubstring(t *testing.T) {
+	fakeHID(t, fakeBus)
+	devices, err := EnumerateDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := devicePaths(FilterByProductSubstring(devices, "pm5")); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("FilterByProductSubstring(pm5) = %v, want [a b]", got)
+	}
+	if got := FilterByProductSubstring(nil, "PM5"); len(got) != 0 {
+		t.Errorf("FilterByProductSubstring(nil) = %v, want empty", got)
+	}
+}
+
+func TestWatchDevicesReportsHotPlug(t *testing.T) {
+	release, _ := fakeHID(t, nil)
+	orig := watchInterval
+	watchInterval = time.Millisecond
+	t.Cleanup(func() { watchInterval = orig })
+
+	pm5b := fakePM5
+	pm5b.Path, pm5b.SerialNbr = "/dev/hidraw1", "430000002"
+	scans := [][]hid.DeviceInfo{{fakePM5}, {fakePM5, pm5b}, {fakePM5}}
+
+	// After the scripted scans the next enumeration blocks, so that the watcher
+	// is parked when ctx is cancelled and no scan outlives the test
+	var calls int
+	parked := make(chan struct{})
+	finished := make(chan struct{})
+	hidEnumerate = func(vid, pid uint16, fn hid.EnumFunc) error {
+		if calls == len(scans) {
+			close(parked)
+			<-release
+			close(finished)
+			return nil
+		}
+		scan := scans[calls]
+		calls++
+		for i := range scan {
+			if err := fn(&scan[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := WatchDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DeviceEvent{
+		{DeviceAdded, DeviceInfo{Path: fakePM5.Path}},
+		{DeviceAdded, DeviceInfo{Path: pm5b.Path}},
+		{DeviceRemoved, DeviceInfo{Path: pm5b.Path}},
+	}
+	for _, w := range want {
+		select {
+		case ev := <-events:
+			if ev.Type != w.Type || ev.Info.Path != w.Info.Path {
+				t.Errorf("event = %v %s, want %v %s", ev.Type, ev.Info.Path, w.Type, w.Info.Path)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %v %s", w.Type, w.Info.Path)
+		}
+	}
+
+	<-parked
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("unexpected event after the scripted scans")
+	}
+	close(release)
+	<-finished
+}
+
+// fakeHandle is an HID handle whose operations return fixed results
+type fakeHandle struct {
+	written  int
+	writeErr error
+	read     []byte
+	readErr  error
+	closeErr error
+}
+
+func (h *fakeHandle) Write(p []byte) (int, error) {
+	return h.written, h.writeErr
+}
+
+func (h *fakeHandle) ReadWithTimeout(p []byte, timeout time.Duration) (int, error) {
+	return copy(p, h.read), h.readErr
+}
+
+func (h *fakeHandle) Close() error {
+	return h.closeErr
+}
+
+// openUSB returns a USBDevice open on handle
+func openUSB(handle hidHandle) *USBDevice {
+	d := NewUSBDevice(DeviceInfo{Path: "fake"})
+	d.device = handle
+	d.isOpen = true
+	return d
+}
+
+func TestUSBDeviceErrors(t *testing.T) {
+	hidErr := errors.New("hid failure")
+
+	tests := []struct {
+		name string
+		op   func() error
+		want error
+	}{
+		{"write on closed device", func() error {
+			_, err := NewUSBDevice(DeviceInfo{}).Write([]byte{0xF1})
+			return err
+		}, ErrDeviceNotOpen},
+		{"read on closed device", func() error {
+			_, err := NewUSBDevice(DeviceInfo{}).Read(time.Millisecond)
+			return err
+		}, ErrDeviceNotOpen},
+		{"write failure", func() error {
+			_, err := openUSB(&fakeHandle{writeErr: hidErr}).Write([]byte{0xF1})
+			return err
+		}, ErrWriteFailed},
+		{"nothing written", func() error {
+			_, err := openUSB(&fakeHandle{}).Write([]byte{0xF1})
+			return err
+		}, ErrWriteFailed},
+		{"read failure", func() error {
+			_, err := openUSB(&fakeHandle{readErr: hidErr}).Read(time.Millisecond)
+			return err
+		}, ErrReadFailed},
+		{"read timeout", func() error {
+			_, err := openUSB(&fakeHandle{readErr: hid.ErrTimeout}).Read(time.Millisecond)
+			return err
+		}, ErrTimeout},
+		{"empty read", func() error {
+			_, err := openUSB(&fakeHandle{}).Read(time.Millisecond)
+			return err
+		}, ErrTimeout},
+		{"report ID only", func() error {
+			_, err := openUSB(&fakeHandle{read: []byte{0x01}}).Read(time.Millisecond)
+			return err
+		}, ErrReadFailed},
+		{"close failure", func() error {
+			return openUSB(&fakeHandle{closeErr: hidErr}).Close()
+		}, ErrCloseFailed},
+		{"already open", func() error {
+			return openUSB(&fakeHandle{}).Open()
+		}, ErrDeviceAlreadyOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.op()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Wrapped HID errors remain reachable alongside the sentinel
+	_, err := openUSB(&fakeHandle{readErr: hidErr}).Read(time.Millisecond)
+	if !errors.Is(err, hidErr) {
+		t.Errorf("read error %v does not wrap the HID error", err)
+	}
+}
+
+func TestUSBDeviceOpenErrors(t *testing.T) {
+	release, opened := fakeHID(t, nil)
+	close(release)
+
+	err := NewUSBDevice(DeviceInfo{Path: "/dev/hidraw9"}).Open()
+	<-opened
+	if !errors.Is(err, ErrOpenFailed) {
+		t.Errorf("Open() on a failing path error = %v, want ErrOpenFailed", err)
+	}
+
+	// With no path or serial the first PM is looked up, and there is none
+	if err := NewUSBDevice(DeviceInfo{}).Open(); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Open() with no devices error = %v, want ErrDeviceNotFound", err)
+	}
+}
+
+func TestUSBDeviceRead(t *testing.T) {
+	// Report ID 1 payloads are capped at the report size
+	read := append([]byte{0x01}, make([]byte, ReportID2Size-1)...)
+	data, err := openUSB(&fakeHandle{read: read}).Read(time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != ReportID1Size-1 {
+		t.Errorf("Read() returned %d bytes, want %d", len(data), ReportID1Size-1)
+	}
+}
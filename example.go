@@ -78,9 +78,9 @@ func runExamples(pm *pm5.PM5) {
 	} else {
 		fmt.Printf("Manufacturer ID: %d\n", version.ManufacturerID)
 		fmt.Printf("Class ID: %d\n", version.ClassID)
-		fmt.Printf("Model: PM%d\n", version.Model)
-		fmt.Printf("HW Version: %d\n", version.HWVersion)
-		fmt.Printf("SW Version: %d\n", version.SWVersion)
+		fmt.Printf("Model: %s\n", version.Model)
+		fmt.Printf("HW Version: %d.%d\n", version.HWVersionHigh(), version.HWVersionLow())
+		fmt.Printf("SW Version: %d.%d\n", version.SWVersionHigh(), version.SWVersionLow())
 	}
 
 	firmwareVersion, err := pm.GetFirmwareVersion()
@@ -179,7 +179,7 @@ func runExamples(pm *pm5.PM5) {
 	// fmt.Printf("Stroke Rate: %d spm\n", strokeRate)
 
 	// hr, _ := pm.GetHeartRate()
-	// if hr == 255 {
+	// if !pm5.IsHeartRateValid(hr) {
 	// 	fmt.Println("Heart Rate: No HR belt connected")
 	// } else {
 	// 	fmt.Printf("Heart Rate: %d bpm\n", hr)
@@ -319,7 +319,7 @@ func monitorWorkout(pm *pm5.PM5) {
 			if err == nil {
 				fmt.Printf("Stroke: Distance=%.2fm, DriveTime=%.2fs, Force=%.1flbs\n",
 					float64(stats.StrokeDistance)/100.0,
-					float64(stats.DriveTIme)/100.0,
+					float64(stats.DriveTime)/100.0,
 					float64(stats.PeakDriveForce)/10.0)
 			}
 		}
@@ -0,0 +1,75 @@
+package pm5
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+	"github.com/danhigham/pm5/device"
+)
+
+// statusReady is a response status byte: state machine Ready, previous frame OK
+const statusReady byte = 0x01
+
+// newTestPM5 returns a PM5 connected to a mock device, paced by a fake clock so
+// that tests do not wait out the interframe gap
+func newTestPM5(t *testing.T) (*PM5, *device.MockDevice, *fakeClock) {
+	t.Helper()
+	dev := device.NewMockDevice()
+	p := New(dev)
+	clock := newFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p.SetClock(clock)
+	if err := p.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	return p, dev, clock
+}
+
+// cmdData builds one command response: command byte, length, data
+func cmdData(cmd byte, data ...byte) []byte {
+	return append([]byte{cmd, byte(len(data))}, data...)
+}
+
+// pmData builds a PM wrapper response holding the given command responses
+func pmData(wrapper byte, cmds ...[]byte) []byte {
+	var inner []byte
+	for _, c := range cmds {
+		inner = append(inner, c...)
+	}
+	return cmdData(wrapper, inner...)
+}
+
+// responseFrame encodes a response frame with the given status byte and
+// command responses
+func responseFrame(t *testing.T, status byte, cmds ...[]byte) []byte {
+	t.Helper()
+	contents := []byte{status}
+	for _, c := range cmds {
+		contents = append(contents, c...)
+	}
+	frame, err := csafe.EncodeFrame(&csafe.Frame{Contents: contents})
+	if err != nil {
+		t.Fatalf("EncodeFrame: %v", err)
+	}
+	return frame
+}
+
+// queueResponse queues an encoded response frame on the mock device
+func queueResponse(t *testing.T, dev *device.MockDevice, status byte, cmds ...[]byte) {
+	t.Helper()
+	dev.QueueResponse(responseFrame(t, status, cmds...))
+}
+
+// decodeWritten decodes every frame written to the mock device
+func decodeWritten(t *testing.T, dev *device.MockDevice) []*csafe.Frame {
+	t.Helper()
+	var frames []*csafe.Frame
+	for _, w := range dev.GetWritten() {
+		f, err := csafe.DecodeFrame(w)
+		if err != nil {
+			t.Fatalf("DecodeFrame(% X): %v", w, err)
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
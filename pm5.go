@@ -3,12 +3,15 @@
 package pm5
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/danhigham/pm5/csafe"
 	"github.com/danhigham/pm5/device"
@@ -18,17 +21,125 @@ var (
 	ErrNotConnected    = errors.New("not connected to PM5")
 	ErrInvalidResponse = errors.New("invalid response from PM5")
 	ErrCommandFailed   = errors.New("command failed")
+	ErrUnsupported     = errors.New("command not supported by PM")
+
+	// Specific previous-frame statuses; each also matches ErrCommandFailed
+	ErrCommandRejected = errors.New("command rejected")
+	ErrCommandBad      = errors.New("bad command")
+	ErrDeviceNotReady  = errors.New("device not ready")
+
+	// ErrNotResent is returned when a dropped command was not idempotent, so it
+	// was not sent again after reconnecting
+	ErrNotResent = errors.New("command not resent after reconnect")
+
+	// ErrFirmwareReflashRequired is returned by RecoverFromBootloader when a
+	// reset does not bring the PM out of the bootloader
+	ErrFirmwareReflashRequired = errors.New("firmware reflash required (use Concept2 Utility)")
+)
+
+// TimeoutError is returned when a response does not complete within the command timeout
+// Partial holds any bytes read before giving up, to help diagnose truncated frames
+type TimeoutError struct {
+	Partial []byte
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	if len(e.Partial) > 0 {
+		return fmt.Sprintf("timed out waiting for response after %d bytes: %v", len(e.Partial), e.Err)
+	}
+	return fmt.Sprintf("timed out waiting for response: %v", e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ExchangeError attaches the sequence number of the command exchange that
+// failed, matching the number in debug trace lines
+type ExchangeError struct {
+	Seq uint64
+	Err error
+}
+
+func (e *ExchangeError) Error() string {
+	return fmt.Sprintf("exchange %d: %v", e.Seq, e.Err)
+}
+
+func (e *ExchangeError) Unwrap() error {
+	return e.Err
+}
+
+// ConnectionState is the state reported to an OnStateChange callback
+type ConnectionState int
+
+const (
+	ConnectionDisconnected ConnectionState = iota
+	ConnectionConnected
+	ConnectionLost // The device stopped responding; Disconnect and Connect to recover
 )
 
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionDisconnected:
+		return "Disconnected"
+	case ConnectionConnected:
+		return "Connected"
+	case ConnectionLost:
+		return "Lost"
+	default:
+		return "Unknown"
+	}
+}
+
 // PM5 represents a connection to a Concept2 PM5 rowing computer
+// A PM5 is safe for concurrent use. Commands are serialized by mu, so only one
+// frame is in flight and the interframe gap holds across goroutines. The
+// connection state read by IsConnected, State and DeviceInfo is also guarded
+// by stateMu, a separate RWMutex, so those calls return at once even while
+// another goroutine is mid-command.
 type PM5 struct {
 	device        device.HIDDevice
 	mu            sync.Mutex
+	stateMu       sync.RWMutex // Written with mu also held, so holding either allows reads
 	connected     bool
-	frameToggle   bool
+	deviceInfo    device.DeviceInfo
+	frameToggle   bool // Toggle bit of the last response
+	toggleKnown   bool // frameToggle is valid: no response has gone unseen since
+	toggleCheck   bool
 	interframeDur time.Duration
 	lastCommand   time.Time
 	debug         bool
+	clock         Clock
+	readTimeout   time.Duration
+	ergType       csafe.ErgMachineType
+	ergTypeKnown  bool
+	maxFrameLen   int
+	minGap        time.Duration // PM-reported minimum interframe gap; 0 until GetCapabilities
+	workoutType   csafe.WorkoutType
+	workoutKnown  bool
+	intervalTotal int
+	staleResponse bool
+	reconnects    int
+	resendUnsafe  bool
+	strictParse   bool
+	encodeBuf     []byte
+	contentsBuf   []byte
+	seq           uint64
+	retry         RetryPolicy
+	state         ConnectionState
+	onState       func(ConnectionState)
+	stateDone     chan struct{} // Closed when the last state callback returns
+}
+
+// RetryPolicy controls resending a command whose response timed out or could
+// not be read, as happens with long USB cables
+// The same frame is resent; host frames carry no frame-toggle bit, so the PM
+// sees an identical request.
+type RetryPolicy struct {
+	MaxAttempts  int           // Total attempts including the first; 0 or 1 disables retries
+	InitialDelay time.Duration // Wait before the first retry
+	Multiplier   float64       // Growth of the wait per retry; values below 1 are treated as 1
 }
 
 // New creates a new PM5 instance with the given HID device
@@ -36,6 +147,8 @@ func New(dev device.HIDDevice) *PM5 {
 	return &PM5{
 		device:        dev,
 		interframeDur: time.Duration(csafe.MinInterframeGapMs) * time.Millisecond,
+		clock:         realClock{},
+		readTimeout:   device.DefaultReadTimeout,
 	}
 }
 
@@ -48,15 +161,32 @@ func (p *PM5) Connect() error {
 		return nil
 	}
 
-	if err := p.device.Open(); err != nil {
-		return fmt.Errorf("failed to open device: %w", err)
+	// The device may already be open, e.g. from device.FindFirstPM5WithTimeout
+	if !p.device.IsOpen() {
+		if err := p.device.Open(); err != nil {
+			return fmt.Errorf("failed to open device: %w", err)
+		}
 	}
 
+	info := p.device.GetInfo()
+	p.stateMu.Lock()
 	p.connected = true
+	p.deviceInfo = info
+	p.stateMu.Unlock()
+
+	p.ergTypeKnown = false
+	p.maxFrameLen = 0
+	p.minGap = 0
+	p.workoutKnown = false
+	p.intervalTotal = 0
+	p.staleResponse = false
+	p.toggleKnown = false
+	p.setState(ConnectionConnected)
 	return nil
 }
 
 // Disconnect closes the connection to the PM5
+// Calling it when already disconnected does nothing and returns nil.
 func (p *PM5) Disconnect() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -69,17 +199,72 @@ func (p *PM5) Disconnect() error {
 		return fmt.Errorf("failed to close device: %w", err)
 	}
 
+	p.stateMu.Lock()
 	p.connected = false
+	p.stateMu.Unlock()
+	p.setState(ConnectionDisconnected)
 	return nil
 }
 
-// IsConnected returns whether the PM5 is connected
-func (p *PM5) IsConnected() bool {
+// OnStateChange registers fn to be called when the connection state changes:
+// on Connect, on Disconnect, when a command fails because the device has gone,
+// and when auto-reconnect brings it back
+// fn runs on its own goroutine, so it may call PM5 methods, and calls are
+// delivered one at a time in the order the changes happened. Pass nil to stop.
+func (p *PM5) OnStateChange(fn func(ConnectionState)) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.onState = fn
+}
+
+// setState records a connection state and notifies the callback if it changed;
+// the caller must hold p.mu
+func (p *PM5) setState(state ConnectionState) {
+	if state == p.state {
+		return
+	}
+	p.stateMu.Lock()
+	p.state = state
+	p.stateMu.Unlock()
+	if p.onState == nil {
+		return
+	}
+
+	fn, prev, done := p.onState, p.stateDone, make(chan struct{})
+	p.stateDone = done
+	go func() {
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		fn(state)
+	}()
+}
+
+// IsConnected returns whether the PM5 is connected
+// It does not wait for a command in progress.
+func (p *PM5) IsConnected() bool {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
 	return p.connected
 }
 
+// State returns the current connection state without waiting for a command in
+// progress
+func (p *PM5) State() ConnectionState {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
+	return p.state
+}
+
+// DeviceInfo returns the details of the device as of the last Connect without
+// waiting for a command in progress
+func (p *PM5) DeviceInfo() device.DeviceInfo {
+	p.stateMu.RLock()
+	defer p.stateMu.RUnlock()
+	return p.deviceInfo
+}
+
 // SetDebug enables or disables hex debug output to stdout
 func (p *PM5) SetDebug(enabled bool) {
 	p.mu.Lock()
@@ -87,16 +272,238 @@ func (p *PM5) SetDebug(enabled bool) {
 	p.debug = enabled
 }
 
+// SetCommandTimeout sets how long to wait for a response to each command
+func (p *PM5) SetCommandTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readTimeout = timeout
+}
+
+// SetMinInterframeGap sets the pause enforced between frames sent to the PM
+// Longer gaps help slow or flaky links. The gap cannot go below the minimum
+// the PM reports through GetCapabilities, or below csafe.MinInterframeGapMs
+// before capabilities have been read; shorter values are raised to that floor.
+func (p *PM5) SetMinInterframeGap(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	floor := time.Duration(csafe.MinInterframeGapMs) * time.Millisecond
+	if p.minGap > 0 {
+		floor = p.minGap
+	}
+	p.interframeDur = max(d, floor)
+}
+
+// SetAutoReconnect enables recovery from USB dropouts
+// When a command fails with a read, write, or timeout error and the device
+// implements device.Reconnecter, the device is reconnected and the command
+// retried up to retries times. Zero (the default) disables it.
+// Commands that are not idempotent (see csafe.IsIdempotent) are not resent;
+// the device is still reconnected and ErrNotResent returned.
+func (p *PM5) SetAutoReconnect(retries int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reconnects = retries
+}
+
+// SetRetryPolicy sets how commands are retried after a read timeout or failure
+// The zero policy (the default) disables retries. As with auto-reconnect,
+// commands that are not idempotent are only retried with SetResendNonIdempotent.
+func (p *PM5) SetRetryPolicy(policy RetryPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retry = policy
+}
+
+// SetResendNonIdempotent lets auto-reconnect and the retry policy resend
+// commands such as screen state changes and resets, which may be applied twice
+// if only the response was lost
+func (p *PM5) SetResendNonIdempotent(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resendUnsafe = enabled
+}
+
+// LastSequence returns the sequence number of the most recent command exchange
+// Numbers start at 1 and increase by one for every frame written, including
+// resends after a reconnect.
+func (p *PM5) LastSequence() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seq
+}
+
+// SetStrictParsing makes responses whose command byte counts overrun the frame
+// fail with csafe.ErrTruncatedCommand instead of returning partial data
+func (p *PM5) SetStrictParsing(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.strictParse = enabled
+}
+
+// SetFrameToggleCheck enables or disables rejecting responses whose frame
+// toggle bit did not flip (off by default)
+// The PM flips the bit in every response, so an unchanged bit means the
+// response is a repeat rather than the answer to the latest frame. Host frames
+// carry no toggle, so there is nothing to encode on the way out. The check is
+// off by default since mock and replay devices often return the same canned
+// status byte; the first response after Connect or a reconnect is always
+// accepted.
+func (p *PM5) SetFrameToggleCheck(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.toggleCheck = enabled
+}
+
+// SetClock replaces the time source used for command pacing
+// Intended for tests; production code uses the real clock by default
+func (p *PM5) SetClock(c Clock) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clock = c
+}
+
+// ctxPollInterval is how often a context-aware read checks for cancellation
+const ctxPollInterval = 20 * time.Millisecond
+
 // sendCommand sends a CSAFE command and returns the response
 func (p *PM5) sendCommand(contents []byte) (*csafe.Response, error) {
+	return p.sendCommandCtx(context.Background(), contents)
+}
+
+// reconnectDelay gives a re-plugged PM time to enumerate before reopening it
+const reconnectDelay = 250 * time.Millisecond
+
+// sendCommandCtx sends a CSAFE command, abandoning the wait for the response
+// if ctx is canceled
+func (p *PM5) sendCommandCtx(ctx context.Context, contents []byte) (*csafe.Response, error) {
+	firstSeq := p.seq
+	resend := p.resendUnsafe || csafe.IsIdempotent(contents)
+	resp, err := p.sendCommandRetry(ctx, contents, resend)
+
+	rc, ok := p.device.(device.Reconnecter)
+	cmdErr := err
+	for attempt := 1; ok && attempt <= p.reconnects && isDeviceDropout(err) && ctx.Err() == nil; attempt++ {
+		if p.debug {
+			log.Printf("Reconnecting after %v (attempt %d/%d)", err, attempt, p.reconnects)
+		}
+		p.clock.Sleep(reconnectDelay)
+		if err = rc.Reconnect(); err != nil {
+			continue
+		}
+		p.staleResponse = false
+		p.toggleKnown = false
+		p.setState(ConnectionConnected)
+		if !resend {
+			return nil, &ExchangeError{Seq: p.seq, Err: fmt.Errorf("%w: %w", ErrNotResent, cmdErr)}
+		}
+		resp, err = p.sendCommandRetry(ctx, contents, resend)
+	}
+
+	if p.connected && isDisconnect(err) {
+		p.setState(ConnectionLost)
+	}
+
+	// Errors from before any frame was written have no exchange to point at
+	if err != nil && p.seq != firstSeq {
+		return resp, &ExchangeError{Seq: p.seq, Err: err}
+	}
+	return resp, err
+}
+
+// sendCommandRetry is sendCommandOnce with the retry policy applied to read
+// timeouts and failures; resend is false for commands unsafe to repeat
+func (p *PM5) sendCommandRetry(ctx context.Context, contents []byte, resend bool) (*csafe.Response, error) {
+	resp, err := p.sendCommandOnce(ctx, contents)
+
+	delay := p.retry.InitialDelay
+	for attempt := 2; resend && attempt <= p.retry.MaxAttempts && isReadError(err) && ctx.Err() == nil; attempt++ {
+		if p.debug {
+			log.Printf("Retrying after %v in %v (attempt %d/%d)", err, delay, attempt, p.retry.MaxAttempts)
+		}
+		p.clock.Sleep(delay)
+		delay = time.Duration(float64(delay) * max(p.retry.Multiplier, 1))
+		resp, err = p.sendCommandOnce(ctx, contents)
+	}
+
+	return resp, err
+}
+
+// isReadError reports whether err is a response timeout or read failure
+func isReadError(err error) bool {
+	return errors.Is(err, device.ErrTimeout) || errors.Is(err, device.ErrReadFailed)
+}
+
+// isDeviceDropout reports whether err suggests the USB connection was lost
+func isDeviceDropout(err error) bool {
+	return errors.Is(err, device.ErrReadFailed) ||
+		errors.Is(err, device.ErrWriteFailed) ||
+		errors.Is(err, device.ErrTimeout) ||
+		errors.Is(err, device.ErrOpenFailed) ||
+		errors.Is(err, device.ErrDeviceNotFound)
+}
+
+// isDisconnect reports whether err means the PM is gone rather than a single
+// command failing, so polling it further is pointless
+// Timeouts are excluded since a PM can miss one response and recover.
+func isDisconnect(err error) bool {
+	return errors.Is(err, ErrNotConnected) ||
+		errors.Is(err, device.ErrDeviceNotOpen) ||
+		errors.Is(err, device.ErrReadFailed) ||
+		errors.Is(err, device.ErrWriteFailed) ||
+		errors.Is(err, device.ErrOpenFailed) ||
+		errors.Is(err, device.ErrDeviceNotFound)
+}
+
+// traceCaller returns the name of the exported pm5 function that started the
+// current exchange, for debug trace lines
+// The send helpers and unexported functions (including closures) are skipped,
+// so the same method is named whichever path it takes to the device.
+func traceCaller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(1, pcs)])
+
+	self, more := frames.Next()
+	pkg := self.Function[:strings.LastIndex(self.Function, ".")+1]
+	fallback := ""
+	for more {
+		var f runtime.Frame
+		f, more = frames.Next()
+		if fallback == "" && !strings.HasPrefix(f.Function, pkg) {
+			fallback = f.Function
+		}
+		name, ok := strings.CutPrefix(f.Function, pkg)
+		if !ok {
+			continue
+		}
+		method := name[strings.LastIndex(name, ".")+1:]
+		if method != "" && unicode.IsUpper(rune(method[0])) {
+			return f.Function
+		}
+	}
+	return fallback
+}
+
+// sendCommandOnce performs a single write and read of a CSAFE command
+func (p *PM5) sendCommandOnce(ctx context.Context, contents []byte) (*csafe.Response, error) {
 	if !p.connected {
 		return nil, ErrNotConnected
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// A response abandoned by a canceled call may still arrive; drop it so it
+	// is not mistaken for the answer to this command
+	if p.staleResponse {
+		p.device.Read(p.interframeDur)
+		p.staleResponse = false
+	}
 
 	// Enforce minimum inter-frame gap
-	elapsed := time.Since(p.lastCommand)
+	elapsed := p.clock.Now().Sub(p.lastCommand)
 	if elapsed < p.interframeDur {
-		time.Sleep(p.interframeDur - elapsed)
+		p.clock.Sleep(p.interframeDur - elapsed)
 	}
 
 	// Build and encode the frame
@@ -105,15 +512,23 @@ func (p *PM5) sendCommand(contents []byte) (*csafe.Response, error) {
 		Contents: contents,
 	}
 
-	encoded, err := csafe.EncodeFrame(frame)
+	// Encode into a reused buffer to avoid an allocation per command
+	encoded, err := csafe.AppendFrame(p.encodeBuf[:0], frame)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode frame: %w", err)
 	}
+	p.encodeBuf = encoded
+	p.seq++
 
+	// Until this frame's response is parsed, any response the PM sends is unseen
+	// and the toggle bit can no longer be predicted
+	prevToggle, toggleKnown := p.frameToggle, p.toggleKnown
+	p.toggleKnown = false
+
+	var funcName string
 	if p.debug {
-		pc, _, _, _ := runtime.Caller(2)
-		funcName := runtime.FuncForPC(pc).Name()
-		log.Printf("[\033[34m%s\033[0m] \033[31m>> % X\033[0m\n", funcName, encoded)
+		funcName = traceCaller()
+		log.Printf("[\033[34m%s\033[0m #%d] \033[31m>> % X\033[0m\n", funcName, p.seq, encoded)
 	}
 
 	// Write to device with retry logic
@@ -126,7 +541,7 @@ func (p *PM5) sendCommand(contents []byte) (*csafe.Response, error) {
 			if p.debug {
 				log.Printf("Retrying write after %v (attempt %d/%d)", backoff, attempt+1, maxRetries)
 			}
-			time.Sleep(backoff)
+			p.clock.Sleep(backoff)
 		}
 
 		_, writeErr = p.device.Write(encoded)
@@ -139,37 +554,51 @@ func (p *PM5) sendCommand(contents []byte) (*csafe.Response, error) {
 		return nil, fmt.Errorf("failed to write to device after %d attempts: %w", maxRetries, writeErr)
 	}
 
-	p.lastCommand = time.Now()
+	p.lastCommand = p.clock.Now()
 
 	// Read response
-	data, err := p.device.Read(500 * time.Millisecond)
+	data, err := p.readResponse(ctx)
 	if err != nil {
+		if ctx.Err() != nil {
+			p.staleResponse = true
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, device.ErrTimeout) {
+			return nil, &TimeoutError{Err: err}
+		}
 		return nil, fmt.Errorf("failed to read from device: %w", err)
 	}
 
-	if p.debug {
-		pc, _, _, _ := runtime.Caller(2)
-		funcName := runtime.FuncForPC(pc).Name()
-		log.Printf("[\033[34m%s\033[0m] \033[31m<< % X...\033[0m\n", funcName, data[0:50])
-	}
-
-	// Find frame boundaries in response
-	startIdx := -1
-	stopIdx := -1
-	for i, b := range data {
-		if b == csafe.StandardFrameStartFlag || b == csafe.ExtendedFrameStartFlag {
-			startIdx = i
-		}
-		if b == csafe.StopFrameFlag && startIdx >= 0 {
-			stopIdx = i
+	// Large responses span several HID reports; keep reading until the stop flag arrives
+	startIdx, stopIdx := frameBounds(data)
+	for startIdx >= 0 && stopIdx < 0 {
+		more, err := p.readResponse(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				p.staleResponse = true
+				return nil, ctx.Err()
+			}
 			break
 		}
+		data = append(data, more...)
+		startIdx, stopIdx = frameBounds(data)
+	}
+
+	if p.debug {
+		log.Printf("[\033[34m%s\033[0m #%d] \033[31m<< % X...\033[0m\n", funcName, p.seq, data[:min(len(data), 50)])
 	}
 
-	if startIdx < 0 || stopIdx < 0 {
+	if startIdx < 0 {
 		return nil, ErrInvalidResponse
 	}
 
+	// A start flag without a stop flag means the frame was cut short
+	if stopIdx < 0 {
+		partial := make([]byte, len(data))
+		copy(partial, data)
+		return nil, &TimeoutError{Partial: partial, Err: device.ErrTimeout}
+	}
+
 	// Decode the frame
 	respFrame, err := csafe.DecodeFrame(data[startIdx : stopIdx+1])
 	if err != nil {
@@ -177,25 +606,224 @@ func (p *PM5) sendCommand(contents []byte) (*csafe.Response, error) {
 	}
 
 	// Parse the response
-	resp, err := csafe.ParseResponse(respFrame.Contents)
+	parse := csafe.ParseResponse
+	if p.strictParse {
+		parse = csafe.ParseResponseStrict
+	}
+	resp, err := parse(respFrame.Contents)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	// The PM flips the toggle bit for every frame it answers; resynchronize to
+	// it either way so a single repeat does not fail later commands
+	p.frameToggle, p.toggleKnown = resp.FrameToggle, true
+	if p.toggleCheck && toggleKnown && resp.FrameToggle == prevToggle {
+		return nil, fmt.Errorf("%w: frame toggle did not change", ErrInvalidResponse)
+	}
+
 	// Check for errors
-	if resp.PrevFrameStatus == csafe.PrevFrameStatusReject {
-		return resp, ErrCommandFailed
+	var statusErr error
+	switch resp.PrevFrameStatus {
+	case csafe.PrevFrameStatusReject:
+		statusErr = ErrCommandRejected
+	case csafe.PrevFrameStatusBad:
+		statusErr = ErrCommandBad
+	case csafe.PrevFrameStatusNotReady:
+		statusErr = ErrDeviceNotReady
+	}
+	if statusErr != nil {
+		return resp, fmt.Errorf("%w: %w (state: %s)", ErrCommandFailed, statusErr,
+			csafe.StateMachineString(resp.StateMachine))
 	}
 
 	return resp, nil
 }
 
-// sendPMCommand sends a PM-specific command
+// frameBounds returns the indexes of the frame start and stop flags in data,
+// or -1 for a flag that was not found
+// The first start flag marks the frame; later bytes that happen to equal a
+// start flag are payload and must not move it.
+func frameBounds(data []byte) (int, int) {
+	startIdx := -1
+	for i, b := range data {
+		if b == csafe.StandardFrameStartFlag || b == csafe.ExtendedFrameStartFlag {
+			startIdx = i
+			break
+		}
+	}
+	if startIdx < 0 {
+		return -1, -1
+	}
+
+	for i := startIdx + 1; i < len(data); i++ {
+		if data[i] == csafe.StopFrameFlag {
+			return startIdx, i
+		}
+	}
+	return startIdx, -1
+}
+
+// readResponse reads a response within the command timeout
+// Cancellable contexts read in short slices so cancellation is noticed promptly
+func (p *PM5) readResponse(ctx context.Context) ([]byte, error) {
+	if ctx.Done() == nil {
+		return p.device.Read(p.readTimeout)
+	}
+
+	for remaining := p.readTimeout; ; remaining -= ctxPollInterval {
+		data, err := p.device.Read(min(remaining, ctxPollInterval))
+		if err == nil || !errors.Is(err, device.ErrTimeout) {
+			return data, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if remaining <= ctxPollInterval {
+			return nil, err
+		}
+	}
+}
+
+// sendPMCommand sends PM-specific commands under wrapper
+// The wrapper is assembled in a reused buffer, as in sendPMBatch.
 func (p *PM5) sendPMCommand(wrapper byte, pmCmds ...[]byte) (*csafe.Response, error) {
-	contents := csafe.BuildPMCommand(wrapper, pmCmds...)
+	contents := append(p.contentsBuf[:0], wrapper, 0)
+	for _, c := range pmCmds {
+		contents = append(contents, c...)
+	}
+	p.contentsBuf = contents
+	if len(contents)-2 > 0xFF {
+		return nil, fmt.Errorf("%w: %d bytes", csafe.ErrCommandTooLong, len(contents)-2)
+	}
+	contents[1] = byte(len(contents) - 2)
 	return p.sendCommand(contents)
 }
 
+// sendPMGet sends a single PM command under wrapper, building it in place so
+// that frequently polled getters do not allocate a command per call
+func (p *PM5) sendPMGet(wrapper byte, cmd byte, data ...byte) (*csafe.Response, error) {
+	contents := csafe.BuildCommandInto(append(p.contentsBuf[:0], wrapper, 0), cmd, data...)
+	p.contentsBuf = contents
+	contents[1] = byte(len(contents) - 2)
+	return p.sendCommand(contents)
+}
+
+// pmBatchCommand is a command together with the expected length of its response data
+type pmBatchCommand struct {
+	cmd     []byte
+	respLen int
+}
+
+// batchStuffingMargin is the headroom left in each response frame for byte
+// stuffing, whose size cannot be known before the PM answers
+const batchStuffingMargin = 8
+
+// sendPMBatch sends PM commands under wrapper, splitting them across as many
+// frames as needed so that neither the request nor the expected response
+// exceeds the frame limit. Standard CSAFE commands in extra are appended to
+// the final frame. The command responses of every frame are merged in order.
+func (p *PM5) sendPMBatch(ctx context.Context, wrapper byte, cmds []pmBatchCommand, extra []pmBatchCommand) ([]csafe.CommandResponse, error) {
+	// Requests are measured after byte stuffing, leaving room for a stuffed
+	// checksum; responses keep a fixed margin
+	reqBudget := p.frameLimit() - 1
+	respBudget := p.frameLimit() - batchStuffingMargin
+
+	// Frame overhead: start flag, checksum, stop flag, and the wrapper header;
+	// responses also carry the status byte
+	const reqOverhead, respOverhead = 5, 6
+
+	var chunks [][]pmBatchCommand
+	var chunk []pmBatchCommand
+	reqSize, respSize := reqOverhead, respOverhead
+	for _, c := range cmds {
+		cReq, cResp := csafe.StuffedLen(c.cmd), 2+c.respLen
+		if len(chunk) > 0 && (reqSize+cReq > reqBudget || respSize+cResp > respBudget) {
+			chunks = append(chunks, chunk)
+			chunk = nil
+			reqSize, respSize = reqOverhead, respOverhead
+		}
+		chunk = append(chunk, c)
+		reqSize += cReq
+		respSize += cResp
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	// Standard commands ride along in the last frame if they fit
+	extraReq, extraResp := 0, 0
+	for _, c := range extra {
+		extraReq += csafe.StuffedLen(c.cmd)
+		extraResp += 2 + c.respLen
+	}
+	extraSeparate := len(chunks) == 0 || reqSize+extraReq > reqBudget || respSize+extraResp > respBudget
+
+	var merged []csafe.CommandResponse
+	for i, chunk := range chunks {
+		// Assemble the wrapper in a reused buffer; byte 1 is the wrapped length
+		contents := append(p.contentsBuf[:0], wrapper, 0)
+		for _, c := range chunk {
+			contents = append(contents, c.cmd...)
+		}
+		if len(contents)-2 > 0xFF {
+			return nil, fmt.Errorf("%w: %d bytes", csafe.ErrCommandTooLong, len(contents)-2)
+		}
+		contents[1] = byte(len(contents) - 2)
+		if i == len(chunks)-1 && !extraSeparate {
+			for _, c := range extra {
+				contents = append(contents, c.cmd...)
+			}
+		}
+
+		p.contentsBuf = contents
+
+		resp, err := p.sendCommandCtx(ctx, contents)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, resp.CommandData...)
+	}
+
+	if extraSeparate && len(extra) > 0 {
+		var contents []byte
+		for _, c := range extra {
+			contents = append(contents, c.cmd...)
+		}
+		resp, err := p.sendCommandCtx(ctx, contents)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, resp.CommandData...)
+	}
+
+	return merged, nil
+}
+
+// ============================================================================
+// Raw Commands
+// ============================================================================
+
+// SendRaw sends frame contents as given and returns the parsed response
+// It bypasses the typed helpers: contents are not validated, and cached state
+// such as the workout type is not updated by commands that change it. Framing,
+// locking, the interframe gap, retries and auto-reconnect still apply.
+func (p *PM5) SendRaw(contents []byte) (*csafe.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sendCommand(contents)
+}
+
+// SendPMCommands sends PM proprietary commands (each built with
+// csafe.BuildCommand) under wrapper, such as csafe.CmdGetPMCfg, in one frame
+// As with SendRaw, the typed helpers are bypassed; the PM responses are found
+// in each CommandResponse's PMResponses.
+func (p *PM5) SendPMCommands(wrapper byte, cmds ...[]byte) (*csafe.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sendPMCommand(wrapper, cmds...)
+}
+
 // ============================================================================
 // Public CSAFE Commands
 // ============================================================================
@@ -207,6 +835,42 @@ func (p *PM5) GetStatus() (*csafe.Response, error) {
 	return p.sendCommand([]byte{csafe.CmdGetStatus})
 }
 
+// GetStatusCtx is GetStatus with cancellation
+func (p *PM5) GetStatusCtx(ctx context.Context) (*csafe.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sendCommandCtx(ctx, []byte{csafe.CmdGetStatus})
+}
+
+// Ping checks that the PM is responsive without changing any state
+// It sends GetStatus and returns nil only for a well-formed response whose
+// previous frame status is OK. Otherwise the error identifies the failure:
+// device.ErrTimeout for no response, ErrInvalidResponse for a malformed one,
+// and ErrCommandFailed (with ErrCommandRejected, ErrCommandBad or
+// ErrDeviceNotReady) when the PM reports a problem.
+func (p *PM5) Ping() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := p.sendCommand([]byte{csafe.CmdGetStatus})
+	if isMalformedFrame(err) && !errors.Is(err, ErrInvalidResponse) {
+		return fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	return err
+}
+
+// isMalformedFrame reports whether err comes from decoding or parsing a
+// response frame
+func isMalformedFrame(err error) bool {
+	return errors.Is(err, csafe.ErrFrameTooShort) ||
+		errors.Is(err, csafe.ErrInvalidStartFlag) ||
+		errors.Is(err, csafe.ErrInvalidStopFlag) ||
+		errors.Is(err, csafe.ErrInvalidChecksum) ||
+		errors.Is(err, csafe.ErrFrameTooLong) ||
+		errors.Is(err, csafe.ErrInvalidStuffByte) ||
+		errors.Is(err, csafe.ErrTruncatedCommand)
+}
+
 // Reset sends a reset command
 func (p *PM5) Reset() error {
 	p.mu.Lock()
@@ -215,6 +879,49 @@ func (p *PM5) Reset() error {
 	return err
 }
 
+// Bootloader recovery: a PM left in the bootloader by an interrupted firmware
+// update is reset a few times, allowing it time to restart between attempts
+const (
+	bootloaderResetAttempts = 3
+	bootloaderResetDelay    = 2 * time.Second
+)
+
+// RecoverFromBootloader tries to bring a PM out of the bootloader, where an
+// interrupted firmware update can leave it (see OperationalState.IsInBootloader)
+// It does nothing if the PM is not in the bootloader. Otherwise the PM is reset
+// until it leaves, and ErrFirmwareReflashRequired is returned if it never does.
+func (p *PM5) RecoverFromBootloader() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, err := p.operationalState()
+	if err != nil {
+		return err
+	}
+	if !state.IsInBootloader() {
+		return nil
+	}
+
+	for attempt := 1; attempt <= bootloaderResetAttempts; attempt++ {
+		if p.debug {
+			log.Printf("PM in %s state, resetting (attempt %d/%d)", state, attempt, bootloaderResetAttempts)
+		}
+		// The bootloader may not answer the reset; the state read decides
+		p.sendCommand([]byte{csafe.CmdReset})
+		p.clock.Sleep(bootloaderResetDelay)
+
+		state, err = p.operationalState()
+		if err == nil && !state.IsInBootloader() {
+			return nil
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: no response after reset: %w", ErrFirmwareReflashRequired, err)
+	}
+	return fmt.Errorf("%w: PM still in %s state after reset", ErrFirmwareReflashRequired, state)
+}
+
 // GoIdle sends the PM to idle state
 func (p *PM5) GoIdle() error {
 	p.mu.Lock()
@@ -251,12 +958,28 @@ func (p *PM5) GoFinished() error {
 type Version struct {
 	ManufacturerID byte
 	ClassID        byte
-	Model          byte
+	Model          csafe.PMModel
 	HWVersion      uint16
 	SWVersion      uint16
+	HasHWVersion   bool // False if the PM's response ended before the hardware version
+	HasSWVersion   bool // False if the PM's response ended before the software version
 }
 
+// HWVersionHigh returns the high byte of the hardware version
+func (v *Version) HWVersionHigh() byte { return byte(v.HWVersion >> 8) }
+
+// HWVersionLow returns the low byte of the hardware version
+func (v *Version) HWVersionLow() byte { return byte(v.HWVersion) }
+
+// SWVersionHigh returns the high byte of the software version
+func (v *Version) SWVersionHigh() byte { return byte(v.SWVersion >> 8) }
+
+// SWVersionLow returns the low byte of the software version
+func (v *Version) SWVersionLow() byte { return byte(v.SWVersion) }
+
 // GetVersion returns the PM version information
+// Older monitors may return a short response; fields that are absent are left
+// zero and reported through HasHWVersion/HasSWVersion
 func (p *PM5) GetVersion() (*Version, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -266,18 +989,33 @@ func (p *PM5) GetVersion() (*Version, error) {
 		return nil, err
 	}
 
-	if len(resp.CommandData) == 0 || len(resp.CommandData[0].Data) < 7 {
+	if len(resp.CommandData) == 0 {
 		return nil, ErrInvalidResponse
 	}
+	return decodeVersion(resp.CommandData[0].Data)
+}
 
-	data := resp.CommandData[0].Data
-	return &Version{
+// decodeVersion parses a CmdGetVersion payload, tolerating legacy short responses
+// Byte layout: manufacturer, class, model, hardware version (LE), software version (LE)
+func decodeVersion(data []byte) (*Version, error) {
+	if len(data) < 3 {
+		return nil, ErrInvalidResponse
+	}
+
+	v := &Version{
 		ManufacturerID: data[0],
 		ClassID:        data[1],
-		Model:          data[2],
-		HWVersion:      uint16(data[3]) | uint16(data[4])<<8,
-		SWVersion:      uint16(data[5]) | uint16(data[6])<<8,
-	}, nil
+		Model:          csafe.PMModel(data[2]),
+	}
+	if len(data) >= 5 {
+		v.HWVersion = uint16(data[3]) | uint16(data[4])<<8
+		v.HasHWVersion = true
+	}
+	if len(data) >= 7 {
+		v.SWVersion = uint16(data[5]) | uint16(data[6])<<8
+		v.HasSWVersion = true
+	}
+	return v, nil
 }
 
 // GetSerial returns the PM serial number as a string
@@ -297,6 +1035,115 @@ func (p *PM5) GetSerial() (string, error) {
 	return string(resp.CommandData[0].Data), nil
 }
 
+// UserInfo represents the user data returned by the standard CSAFE GetUserInfo
+// Wire layout: Bytes 0-1 weight (little-endian), Byte 2 weight units specifier,
+// Byte 3 age in years, Byte 4 gender.
+type UserInfo struct {
+	WeightKg float64
+	Age      byte
+	Gender   csafe.Gender
+}
+
+// poundsToKg converts pounds to kilograms
+const poundsToKg = 0.45359237
+
+// GetUserInfo returns the user weight, age and gender via the standard CSAFE command
+// This is the cross-machine counterpart to GetUserProfile; the weight is
+// converted to kilograms from whichever units the PM reports.
+func (p *PM5) GetUserInfo() (*UserInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendCommand([]byte{csafe.CmdGetUserInfo})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.CommandData) == 0 || len(resp.CommandData[0].Data) < 5 {
+		return nil, ErrInvalidResponse
+	}
+
+	data := resp.CommandData[0].Data
+	weight := float64(uint16(data[0]) | uint16(data[1])<<8)
+	switch data[2] {
+	case csafe.UnitsKg:
+	case csafe.UnitsKgTenths:
+		weight /= 10
+	case csafe.UnitsLb:
+		weight *= poundsToKg
+	case csafe.UnitsLbTenths:
+		weight *= poundsToKg / 10
+	default:
+		return nil, fmt.Errorf("%w: unknown weight units 0x%02X", ErrInvalidResponse, data[2])
+	}
+
+	return &UserInfo{
+		WeightKg: weight,
+		Age:      data[3],
+		Gender:   csafe.Gender(data[4]),
+	}, nil
+}
+
+// Capabilities represents the CSAFE protocol capabilities reported by the PM
+// Wire layout of the GetCaps response for csafe.CapCodeProtocol: Byte 0 max rx
+// frame, Byte 1 max tx frame, Byte 2 min interframe gap in milliseconds.
+type Capabilities struct {
+	MaxRxFrame       byte          // Largest frame the PM accepts
+	MaxTxFrame       byte          // Largest frame the PM sends
+	MinInterframeGap time.Duration // Minimum gap between frames
+}
+
+// GetCapabilities returns the PM protocol capabilities
+// Sends GetCaps with capability class csafe.CapCodeProtocol (0x00). Returns
+// ErrUnsupported if the PM rejects the request; FrameLimit then falls back to
+// csafe.MaxFrameLength.
+func (p *PM5) GetCapabilities() (*Capabilities, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendCommand(csafe.BuildCommand(csafe.CmdGetCaps, csafe.CapCodeProtocol))
+	if errors.Is(err, ErrCommandRejected) {
+		p.maxFrameLen = csafe.MaxFrameLength
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.CmdGetCaps && len(cr.Data) >= 3 {
+			caps := &Capabilities{
+				MaxRxFrame:       cr.Data[0],
+				MaxTxFrame:       cr.Data[1],
+				MinInterframeGap: time.Duration(cr.Data[2]) * time.Millisecond,
+			}
+			p.maxFrameLen = min(int(caps.MaxRxFrame), csafe.MaxFrameLength)
+			p.minGap = caps.MinInterframeGap
+			return caps, nil
+		}
+	}
+
+	p.maxFrameLen = csafe.MaxFrameLength
+	return nil, ErrUnsupported
+}
+
+// FrameLimit returns the largest frame that may be sent to the PM
+// This is the negotiated limit from GetCapabilities, or csafe.MaxFrameLength if
+// capabilities are unknown or unsupported
+func (p *PM5) FrameLimit() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.frameLimit()
+}
+
+// frameLimit returns the frame limit; the caller must hold p.mu
+func (p *PM5) frameLimit() int {
+	if p.maxFrameLen > 0 {
+		return p.maxFrameLen
+	}
+	return csafe.MaxFrameLength
+}
+
 // WorkTime represents elapsed work time
 type WorkTime struct {
 	Hours      byte
@@ -417,7 +1264,16 @@ func (p *PM5) GetPower() (uint16, error) {
 	return uint16(data[0]) | uint16(data[1])<<8, nil
 }
 
-// GetHeartRate returns the current heart rate
+// HeartRateInvalid is the heart rate the PM reports when no belt is paired
+const HeartRateInvalid byte = 255
+
+// IsHeartRateValid reports whether bpm is a real reading rather than
+// HeartRateInvalid or 0 (belt paired but no beat detected)
+func IsHeartRateValid(bpm byte) bool {
+	return bpm != HeartRateInvalid && bpm != 0
+}
+
+// GetHeartRate returns the current heart rate (HeartRateInvalid = no HR belt)
 func (p *PM5) GetHeartRate() (byte, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -434,12 +1290,65 @@ func (p *PM5) GetHeartRate() (byte, error) {
 	return resp.CommandData[0].Data[0], nil
 }
 
+// HeartRateReading represents a decoded heart rate response
+type HeartRateReading struct {
+	BPM     byte
+	Valid   bool // False when no belt is paired (HeartRateInvalid) or no beat detected (0)
+	Zone    byte // Heart rate zone, only meaningful when HasZone is set
+	HasZone bool
+}
+
+// GetHeartRateReading returns the current heart rate with its validity and,
+// when the PM reports one, the heart rate zone
+func (p *PM5) GetHeartRateReading() (*HeartRateReading, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendCommand([]byte{csafe.CmdGetHRCur})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.CmdGetHRCur && len(cr.Data) >= 1 {
+			return decodeHeartRate(cr.Data), nil
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// decodeHeartRate decodes a CmdGetHRCur payload
+// Byte 0 is the rate in BPM; an optional byte 1 carries the zone
+func decodeHeartRate(data []byte) *HeartRateReading {
+	reading := &HeartRateReading{
+		BPM:   data[0],
+		Valid: IsHeartRateValid(data[0]),
+	}
+	if len(data) >= 2 {
+		reading.Zone = data[1]
+		reading.HasZone = true
+	}
+	return reading
+}
+
 // SetProgram sets a predefined workout program
 func (p *PM5) SetProgram(workoutNum csafe.WorkoutNumber) error {
+	return p.SetProgramLevel(workoutNum, 0)
+}
+
+// SetProgramLevel sets a predefined workout program with a CSAFE program level
+// The level is the second byte of CSAFE SetProgram; the PM ignores it, so
+// SetProgram always sends 0
+func (p *PM5) SetProgramLevel(workoutNum csafe.WorkoutNumber, level byte) error {
+	if workoutNum > csafe.WorkoutNumberCustom5 {
+		return fmt.Errorf("workout number %d out of range (max %d)", workoutNum, csafe.WorkoutNumberCustom5)
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	cmd := csafe.BuildCommand(csafe.CmdSetProgram, byte(workoutNum), 0x00)
+	cmd := csafe.BuildCommand(csafe.CmdSetProgram, byte(workoutNum), level)
 	_, err := p.sendCommand(cmd)
 	return err
 }
@@ -467,6 +1376,37 @@ func (p *PM5) SetHorizontal(distance uint16) error {
 	return err
 }
 
+// SetDistanceGoal sets the distance goal in meters without the uint16 limit of SetHorizontal
+// Distances up to 65535m are sent in meters and whole kilometers beyond that in
+// km; any other distance is programmed through the proprietary workout duration
+func (p *PM5) SetDistanceGoal(meters uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	switch {
+	case meters <= 0xFFFF:
+		_, err = p.sendCommand(csafe.BuildCommand(csafe.CmdSetHorizontal,
+			byte(meters&0xFF),
+			byte((meters>>8)&0xFF),
+			csafe.UnitsMeter))
+	case meters%1000 == 0 && meters/1000 <= 0xFFFF:
+		km := meters / 1000
+		_, err = p.sendCommand(csafe.BuildCommand(csafe.CmdSetHorizontal,
+			byte(km&0xFF),
+			byte((km>>8)&0xFF),
+			csafe.UnitsKm))
+	default:
+		_, err = p.sendPMCommand(csafe.CmdSetPMCfg, csafe.BuildCommand(csafe.PMCmdSetWorkoutDuration,
+			byte(csafe.DurationTypeDistance),
+			byte((meters>>24)&0xFF),
+			byte((meters>>16)&0xFF),
+			byte((meters>>8)&0xFF),
+			byte(meters&0xFF)))
+	}
+	return err
+}
+
 // SetCalories sets the calorie goal
 func (p *PM5) SetCalories(calories uint16) error {
 	p.mu.Lock()
@@ -1,6 +1,12 @@
 package pm5
 
 import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/danhigham/pm5/csafe"
 )
 
@@ -18,8 +24,7 @@ func (p *PM5) GetFirmwareVersion() (*FirmwareVersion, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetFWVersion)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetFWVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -51,8 +56,7 @@ func (p *PM5) GetHardWareVersion() (*HardwareVersion, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetHWVersion)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetHWVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -80,8 +84,7 @@ func (p *PM5) GetHardwareAddress() (uint32, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetHWAddress)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetHWAddress)
 	if err != nil {
 		return 0, err
 	}
@@ -103,8 +106,7 @@ func (p *PM5) GetWorkoutType() (csafe.WorkoutType, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetWorkoutType)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetWorkoutType)
 	if err != nil {
 		return 0, err
 	}
@@ -118,21 +120,20 @@ func (p *PM5) GetWorkoutType() (csafe.WorkoutType, error) {
 	return 0, ErrInvalidResponse
 }
 
-// GetWorkoutState returns the current workout state
-func (p *PM5) GetWorkoutState() (csafe.WorkoutState, error) {
+// GetDisplayType returns the current display format
+func (p *PM5) GetDisplayType() (csafe.DisplayFormatType, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetWorkoutState)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetDisplayType)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
 		if pmResp := cr.FirstPMResponse(); pmResp != nil {
-			if pmResp.Command == csafe.PMCmdGetWorkoutState && len(pmResp.Data) >= 1 {
-				return csafe.WorkoutState(pmResp.Data[0]), nil
+			if pmResp.Command == csafe.PMCmdGetDisplayType && len(pmResp.Data) >= 1 {
+				return csafe.DisplayFormatType(pmResp.Data[0]), nil
 			}
 		}
 	}
@@ -140,63 +141,93 @@ func (p *PM5) GetWorkoutState() (csafe.WorkoutState, error) {
 	return 0, ErrInvalidResponse
 }
 
-// GetIntervalType returns the current interval type
-func (p *PM5) GetIntervalType() (csafe.IntervalType, error) {
+// GetDisplayUnits returns the current display units
+func (p *PM5) GetDisplayUnits() (csafe.DisplayUnitsType, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetIntervalType)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetDisplayUnits)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetIntervalType && len(cr.Data) >= 1 {
-			return csafe.IntervalType(cr.Data[0]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetDisplayUnits && len(pmResp.Data) >= 1 {
+				return csafe.DisplayUnitsType(pmResp.Data[0]), nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetOperationalState returns the current operational state
-func (p *PM5) GetOperationalState() (csafe.OperationalState, error) {
+// ScreenState describes the screen currently shown by the PM
+// Wire layout: Byte 0 screen type, Byte 1 screen value, Byte 2 screen status;
+// on the CSAFE screen an optional Byte 3 carries the CSAFE sub-state
+type ScreenState struct {
+	Type         csafe.ScreenType
+	Value        byte
+	Status       csafe.ScreenStatus
+	SubStatus    byte // CSAFE screen sub-state, valid if HasSubStatus
+	HasSubStatus bool
+}
+
+// Pending reports whether the last screen state change is still being processed
+func (s *ScreenState) Pending() bool {
+	return s.Status == csafe.ScreenStatusPending
+}
+
+// GetScreenState returns the current screen type, value, and status
+// Poll until Pending is false to confirm a SetScreenState or SetDisplayString took effect
+func (p *PM5) GetScreenState() (*ScreenState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetOperationalState)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetScreenStateStatus)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	for _, cr := range resp.CommandData {
 		if pmResp := cr.FirstPMResponse(); pmResp != nil {
-			if pmResp.Command == csafe.PMCmdGetOperationalState && len(pmResp.Data) >= 1 {
-				return csafe.OperationalState(pmResp.Data[0]), nil
+			if pmResp.Command == csafe.PMCmdGetScreenStateStatus && len(pmResp.Data) >= 3 {
+				return decodeScreenState(pmResp.Data), nil
 			}
 		}
 	}
 
-	return 0, ErrInvalidResponse
+	return nil, ErrInvalidResponse
 }
 
-// GetRowingState returns the current rowing state
-func (p *PM5) GetRowingState() (csafe.RowingState, error) {
+// decodeScreenState parses a screen state status payload of at least 3 bytes
+func decodeScreenState(data []byte) *ScreenState {
+	state := &ScreenState{
+		Type:   csafe.ScreenType(data[0]),
+		Value:  data[1],
+		Status: csafe.ScreenStatus(data[2]),
+	}
+	if state.Type == csafe.ScreenTypeCSAFE && len(data) >= 4 {
+		state.SubStatus = data[3]
+		state.HasSubStatus = true
+	}
+	return state
+}
+
+// GetWorkoutState returns the current workout state
+func (p *PM5) GetWorkoutState() (csafe.WorkoutState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetRowingState)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetWorkoutState)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
 		if pmResp := cr.FirstPMResponse(); pmResp != nil {
-			if pmResp.Command == csafe.PMCmdGetRowingState && len(pmResp.Data) >= 1 {
-				return csafe.RowingState(pmResp.Data[0]), nil
+			if pmResp.Command == csafe.PMCmdGetWorkoutState && len(pmResp.Data) >= 1 {
+				return csafe.WorkoutState(pmResp.Data[0]), nil
 			}
 		}
 	}
@@ -204,41 +235,43 @@ func (p *PM5) GetRowingState() (csafe.RowingState, error) {
 	return 0, ErrInvalidResponse
 }
 
-// GetStrokeState returns the current stroke state
-func (p *PM5) GetStrokeState() (csafe.StrokeState, error) {
+// GetIntervalType returns the current interval type
+func (p *PM5) GetIntervalType() (csafe.IntervalType, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetStrokeState)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetIntervalType)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetStrokeState && len(cr.Data) >= 1 {
-			return csafe.StrokeState(cr.Data[0]), nil
+		if cr.Command == csafe.PMCmdGetIntervalType && len(cr.Data) >= 1 {
+			return csafe.IntervalType(cr.Data[0]), nil
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetBatteryLevel returns the battery level percentage
-func (p *PM5) GetBatteryLevel() (byte, error) {
+// GetOperationalState returns the current operational state
+func (p *PM5) GetOperationalState() (csafe.OperationalState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return p.operationalState()
+}
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetBatteryLevelPercent)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+// operationalState is GetOperationalState for callers already holding p.mu
+func (p *PM5) operationalState() (csafe.OperationalState, error) {
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetOperationalState)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
 		if pmResp := cr.FirstPMResponse(); pmResp != nil {
-			if pmResp.Command == csafe.PMCmdGetBatteryLevelPercent && len(pmResp.Data) >= 1 {
-				return pmResp.Data[0], nil
+			if pmResp.Command == csafe.PMCmdGetOperationalState && len(pmResp.Data) >= 1 {
+				return csafe.OperationalState(pmResp.Data[0]), nil
 			}
 		}
 	}
@@ -246,21 +279,20 @@ func (p *PM5) GetBatteryLevel() (byte, error) {
 	return 0, ErrInvalidResponse
 }
 
-// GetErgMachineType returns the connected erg machine type
-func (p *PM5) GetErgMachineType() (csafe.ErgMachineType, error) {
+// GetPowerUpState returns how the PM last started
+func (p *PM5) GetPowerUpState() (csafe.PowerUpState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetErgMachineType)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetPowerUpState)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
 		if pmResp := cr.FirstPMResponse(); pmResp != nil {
-			if pmResp.Command == csafe.PMCmdGetErgMachineType && len(pmResp.Data) >= 1 {
-				return csafe.ErgMachineType(pmResp.Data[0]), nil
+			if pmResp.Command == csafe.PMCmdGetPowerUpState && len(pmResp.Data) >= 1 {
+				return csafe.PowerUpState(pmResp.Data[0]), nil
 			}
 		}
 	}
@@ -268,386 +300,1563 @@ func (p *PM5) GetErgMachineType() (csafe.ErgMachineType, error) {
 	return 0, ErrInvalidResponse
 }
 
-// GetWorkoutIntervalCount returns the current interval count
-func (p *PM5) GetWorkoutIntervalCount() (byte, error) {
+// GetCommunicationState returns the state of the PM communication stack
+// Right after power up, wait for CommunicationStateReady before sending workout commands
+func (p *PM5) GetCommunicationState() (csafe.CommunicationState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetWorkoutIntervalCount)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetCommunicationState)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetWorkoutIntervalCount && len(cr.Data) >= 1 {
-			return cr.Data[0], nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetCommunicationState && len(pmResp.Data) >= 1 {
+				return csafe.CommunicationState(pmResp.Data[0]), nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// ============================================================================
-// PM5 Proprietary Get Data Commands
-// ============================================================================
-
-// GetPMWorkTime returns detailed work time in hundredths of seconds
-func (p *PM5) GetPMWorkTime() (uint32, error) {
+// GetLogCardState returns whether a LogCard is inserted
+func (p *PM5) GetLogCardState() (csafe.LogCardState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetWorkTime)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetLogCardState)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetWorkTime && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetLogCardState && len(pmResp.Data) >= 1 {
+				return csafe.LogCardState(pmResp.Data[0]), nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetPMWorkDistance returns the work distance in tenths of meters
-func (p *PM5) GetPMWorkDistance() (uint32, error) {
+// GetLogCardStatus returns what the inserted LogCard is doing
+func (p *PM5) GetLogCardStatus() (csafe.LogCardStatus, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetWorkDistance)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetLogCardStatus)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetWorkDistance && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetLogCardStatus && len(pmResp.Data) >= 1 {
+				return csafe.LogCardStatus(pmResp.Data[0]), nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetStroke500mPace returns the current pace per 500m in hundredths of seconds
-func (p *PM5) GetStroke500mPace() (uint32, error) {
+// IsLogCardReady reports whether a LogCard is inserted and idle, reading both
+// in a single frame
+// Check it before SetScreenState with ScreenValueWorkoutRefreshLogCard, which
+// the PM rejects when no card is present or the card is busy.
+func (p *PM5) IsLogCardReady() (bool, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetStroke500mPace)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg,
+		csafe.BuildCommand(csafe.PMCmdGetLogCardState),
+		csafe.BuildCommand(csafe.PMCmdGetLogCardStatus))
 	if err != nil {
-		return 0, err
+		return false, err
 	}
 
+	var state csafe.LogCardState
+	var status csafe.LogCardStatus
+	found := 0
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetStroke500mPace && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		for _, pmResp := range cr.PMResponses {
+			if len(pmResp.Data) < 1 {
+				continue
+			}
+			switch pmResp.Command {
+			case csafe.PMCmdGetLogCardState:
+				state = csafe.LogCardState(pmResp.Data[0])
+			case csafe.PMCmdGetLogCardStatus:
+				status = csafe.LogCardStatus(pmResp.Data[0])
+			default:
+				continue
+			}
+			found++
 		}
 	}
 
-	return 0, ErrInvalidResponse
+	if found < 2 {
+		return false, ErrInvalidResponse
+	}
+	return state == csafe.LogCardStatePresent && status == csafe.LogCardStatusIdle, nil
 }
 
-// GetStrokePower returns the current stroke power in watts
-func (p *PM5) GetStrokePower() (uint32, error) {
+// GetRowingState returns the current rowing state
+func (p *PM5) GetRowingState() (csafe.RowingState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetStrokePower)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetRowingState)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetStrokePower && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetRowingState && len(pmResp.Data) >= 1 {
+				return csafe.RowingState(pmResp.Data[0]), nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetStrokeCaloricBurnRate returns the stroke caloric burn rate in cals/hr
-func (p *PM5) GetStrokeCaloricBurnRate() (uint32, error) {
+// GetStrokeState returns the current stroke state
+func (p *PM5) GetStrokeState() (csafe.StrokeState, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetStrokeCaloricBurnRate)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStrokeState)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetStrokeCaloricBurnRate && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetStrokeState && len(pmResp.Data) >= 1 {
+				return csafe.StrokeState(pmResp.Data[0]), nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetStrokeRate returns the current stroke rate (strokes per minute)
-func (p *PM5) GetStrokeRate() (byte, error) {
+// IsActivelyRowing reports whether the athlete is rowing right now
+// Rowing state, stroke state, and stroke rate are read in a single frame. The
+// rowing state can briefly drop to inactive around the catch, so a spinning
+// flywheel with a non-zero stroke rate also counts as rowing.
+func (p *PM5) IsActivelyRowing() (bool, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetStrokeRate)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	contents, err := csafe.BuildPMCommandChecked(csafe.CmdGetPMCfg,
+		csafe.BuildCommand(csafe.PMCmdGetRowingState))
 	if err != nil {
-		return 0, err
+		return false, err
+	}
+	data, err := csafe.BuildPMCommandChecked(csafe.CmdGetPMData,
+		csafe.BuildCommand(csafe.PMCmdGetStrokeState),
+		csafe.BuildCommand(csafe.PMCmdGetStrokeRate))
+	if err != nil {
+		return false, err
+	}
+	resp, err := p.sendCommand(append(contents, data...))
+	if err != nil {
+		return false, err
 	}
 
+	var rowingState csafe.RowingState
+	var strokeState csafe.StrokeState
+	var strokeRate byte
+	found := 0
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetStrokeRate && len(cr.Data) >= 1 {
-			return cr.Data[0], nil
+		for _, pmResp := range cr.PMResponses {
+			if len(pmResp.Data) < 1 {
+				continue
+			}
+			switch pmResp.Command {
+			case csafe.PMCmdGetRowingState:
+				rowingState = csafe.RowingState(pmResp.Data[0])
+			case csafe.PMCmdGetStrokeState:
+				strokeState = csafe.StrokeState(pmResp.Data[0])
+			case csafe.PMCmdGetStrokeRate:
+				strokeRate = pmResp.Data[0]
+			default:
+				continue
+			}
+			found++
 		}
 	}
 
-	return 0, ErrInvalidResponse
+	if found < 3 {
+		return false, ErrInvalidResponse
+	}
+	return isActivelyRowing(rowingState, strokeState, strokeRate), nil
 }
 
-// GetDragFactor returns the current drag factor
-func (p *PM5) GetDragFactor() (byte, error) {
+// isActivelyRowing combines the rowing indicators into a single activity signal
+func isActivelyRowing(rowing csafe.RowingState, stroke csafe.StrokeState, strokeRate byte) bool {
+	if rowing == csafe.RowingStateActive {
+		return true
+	}
+	return strokeRate > 0 && stroke != csafe.StrokeStateWaitingForWheelToReachMinSpeed
+}
+
+// GetBatteryLevel returns the battery level percentage
+func (p *PM5) GetBatteryLevel() (byte, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetDragFactor)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetBatteryLevelPercent)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetDragFactor && len(cr.Data) >= 1 {
-			return cr.Data[0], nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetBatteryLevelPercent && len(pmResp.Data) >= 1 {
+				return pmResp.Data[0], nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetTotalAvg500mPace returns the total average pace per 500m
-func (p *PM5) GetTotalAvg500mPace() (uint32, error) {
+// GetErgMachineType returns the connected erg machine type
+// The machine type cannot change while connected, so it is cached after the first read
+func (p *PM5) GetErgMachineType() (csafe.ErgMachineType, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return p.ergMachineType(context.Background())
+}
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetTotalAvg500mPace)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+// ergMachineType is GetErgMachineType with cancellation, for callers already
+// holding p.mu
+func (p *PM5) ergMachineType(ctx context.Context) (csafe.ErgMachineType, error) {
+	if p.ergTypeKnown {
+		return p.ergType, nil
+	}
+
+	contents, err := csafe.BuildPMCommandChecked(csafe.CmdGetPMCfg,
+		csafe.BuildCommand(csafe.PMCmdGetErgMachineType))
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.sendCommandCtx(ctx, contents)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetTotalAvg500mPace && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetErgMachineType && len(pmResp.Data) >= 1 {
+				p.ergType = csafe.ErgMachineType(pmResp.Data[0])
+				p.ergTypeKnown = true
+				return p.ergType, nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetTotalAvgPower returns the total average power in watts
-func (p *PM5) GetTotalAvgPower() (uint32, error) {
+// GetProductConfiguration returns the raw product configuration payload
+// The layout of this block is not published, so it is returned undecoded for
+// diagnostics; use GetErgMachineType and GetFirmwareVersion for those values.
+func (p *PM5) GetProductConfiguration() ([]byte, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetTotalAvgPower)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetProductConfiguration)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetTotalAvgPower && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetProductConfiguration && len(pmResp.Data) >= 1 {
+				return append([]byte(nil), pmResp.Data...), nil
+			}
 		}
 	}
 
-	return 0, ErrInvalidResponse
+	return nil, ErrInvalidResponse
 }
 
-// GetTotalAvgCalories returns the total calories burned
-func (p *PM5) GetTotalAvgCalories() (uint32, error) {
+// GetWorkoutIntervalCount returns the current interval count
+func (p *PM5) GetWorkoutIntervalCount() (byte, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetTotalAvgCalories)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetWorkoutIntervalCount)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetTotalAvgCalories && len(cr.Data) >= 4 {
-			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
-				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		if cr.Command == csafe.PMCmdGetWorkoutIntervalCount && len(cr.Data) >= 1 {
+			return cr.Data[0], nil
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetAvgHeartRate returns the average heart rate
-func (p *PM5) GetAvgHeartRate() (byte, error) {
+// GetIntervalProgress returns the current interval (1-based) and the total
+// number of intervals, e.g. for "interval 3 of 5"
+// The PM does not report the total, so it is only known when the intervals were
+// programmed through SetWorkoutIntervalCount on this PM5; otherwise total is 0.
+func (p *PM5) GetIntervalProgress() (current int, total int, err error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetAvgHeartRate)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetWorkoutIntervalCount)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetAvgHeartRate && len(cr.Data) >= 1 {
-			return cr.Data[0], nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetWorkoutIntervalCount && len(pmResp.Data) >= 1 {
+				// The PM counts from 0; widen first so interval 255 reports 256
+				return int(pmResp.Data[0]) + 1, p.intervalTotal, nil
+			}
 		}
 	}
 
-	return 0, ErrInvalidResponse
-}
-
-// StrokeStats contains detailed stroke statistics
-type StrokeStats struct {
-	StrokeDistance    uint16 // 0.01m units
-	DriveTIme         byte   // 0.01s units
-	RecoveryTime      uint16 // 0.01s units
-	StrokeLength      byte   // 0.01m units
-	DriveCounter      uint16
-	PeakDriveForce    uint16 // 0.1 lbs
-	ImpulseDriveForce uint16 // 0.1 lbs
-	AvgDriveForce     uint16 // 0.1 lbs
-	WorkPerStroke     uint16 // 0.1 Joules
+	return 0, 0, ErrInvalidResponse
 }
 
-// GetStrokeStats returns detailed stroke statistics
-func (p *PM5) GetStrokeStats() (*StrokeStats, error) {
+// GetWorkoutDuration returns the programmed workout goal
+// The value is in units matching the duration type: 0.01s for time, meters for distance,
+// calories, or watt-minutes
+func (p *PM5) GetWorkoutDuration() (csafe.DurationType, uint32, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetStrokeStats, 0x00)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetWorkoutDuration)
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetStrokeStats && len(cr.Data) >= 16 {
-			d := cr.Data
-			return &StrokeStats{
-				StrokeDistance:    uint16(d[0])<<8 | uint16(d[1]),
-				DriveTIme:         d[2],
-				RecoveryTime:      uint16(d[3])<<8 | uint16(d[4]),
-				StrokeLength:      d[5],
-				DriveCounter:      uint16(d[6])<<8 | uint16(d[7]),
-				PeakDriveForce:    uint16(d[8])<<8 | uint16(d[9]),
-				ImpulseDriveForce: uint16(d[10])<<8 | uint16(d[11]),
-				AvgDriveForce:     uint16(d[12])<<8 | uint16(d[13]),
-				WorkPerStroke:     uint16(d[14])<<8 | uint16(d[15]),
-			}, nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetWorkoutDuration && len(pmResp.Data) >= 5 {
+				return csafe.DurationType(pmResp.Data[0]), BytesToUint32BE(pmResp.Data[1:5]), nil
+			}
 		}
 	}
 
-	return nil, ErrInvalidResponse
+	return 0, 0, ErrInvalidResponse
 }
 
-// GetForcePlotData returns force curve data points
-// blockSize is the number of bytes to read (max 32, returns 16 words)
-func (p *PM5) GetForcePlotData(blockSize byte) ([]uint16, error) {
+// IsWorkoutLoggable reports whether the PM will save the current workout to its log
+// The workout type, workout state and internal log parameters are read in one
+// frame. A PM that returns no log parameters has no internal log, so nothing
+// is loggable. Otherwise Just Row workouts are not logged, and a workout
+// already in the Logged state is reported as loggable. The PM provides no
+// command to change this behavior.
+func (p *PM5) IsWorkoutLoggable() (bool, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if blockSize > 32 {
-		blockSize = 32
+	pmCmds := [][]byte{
+		csafe.BuildCommand(csafe.PMCmdGetWorkoutType),
+		csafe.BuildCommand(csafe.PMCmdGetWorkoutState),
+		csafe.BuildCommand(csafe.PMCmdGetInternalLogParams),
 	}
-
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetForcePlotData, blockSize)
-	resp, err := p.sendPMCommand(csafe.CmdSetUserCfg1, pmCmd)
+	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmds...)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
+	var workoutType csafe.WorkoutType
+	var workoutState csafe.WorkoutState
+	haveType, haveState, haveLog := false, false, false
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetForcePlotData && len(cr.Data) >= 1 {
-			bytesRead := cr.Data[0]
-			if bytesRead == 0 {
-				return []uint16{}, nil
+		for _, pmResp := range cr.PMResponses {
+			switch pmResp.Command {
+			case csafe.PMCmdGetWorkoutType:
+				if len(pmResp.Data) >= 1 {
+					workoutType = csafe.WorkoutType(pmResp.Data[0])
+					haveType = true
+				}
+			case csafe.PMCmdGetWorkoutState:
+				if len(pmResp.Data) >= 1 {
+					workoutState = csafe.WorkoutState(pmResp.Data[0])
+					haveState = true
+				}
+			case csafe.PMCmdGetInternalLogParams:
+				// Bytes 0-3 first entry address, Bytes 4-5 entry count
+				haveLog = len(pmResp.Data) >= 6
 			}
+		}
+	}
 
-			// Parse word data (big-endian pairs)
-			numWords := int(bytesRead) / 2
-			if numWords > 16 {
-				numWords = 16
-			}
+	if !haveType || !haveState {
+		return false, ErrInvalidResponse
+	}
+	if !haveLog {
+		return false, nil
+	}
+
+	if workoutState == csafe.WorkoutStateWorkoutLogged {
+		return true, nil
+	}
+
+	switch workoutType {
+	case csafe.WorkoutTypeJustRowNoSplits, csafe.WorkoutTypeJustRowSplits:
+		return false, nil
+	}
+	return true, nil
+}
+
+// UserProfile represents the user data the PM uses for calorie calculations
+// Wire layout (get and set): Byte 0 age in years, Bytes 1-2 weight in 0.1 kg
+// (big-endian), Byte 3 gender
+type UserProfile struct {
+	Age      byte
+	WeightKg float64
+	Gender   csafe.Gender
+}
+
+// GetUserProfile returns the user profile stored on the PM
+func (p *PM5) GetUserProfile() (*UserProfile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetUserProfile)
+	if err != nil {
+		return nil, err
+	}
 
-			words := make([]uint16, numWords)
-			for i := 0; i < numWords && 1+i*2+1 < len(cr.Data); i++ {
-				words[i] = uint16(cr.Data[1+i*2])<<8 | uint16(cr.Data[1+i*2+1])
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetUserProfile && len(pmResp.Data) >= 4 {
+				d := pmResp.Data
+				return &UserProfile{
+					Age:      d[0],
+					WeightKg: float64(BytesToUint16BE(d[1:3])) / 10.0,
+					Gender:   csafe.Gender(d[3]),
+				}, nil
 			}
-			return words, nil
 		}
 	}
 
 	return nil, ErrInvalidResponse
 }
 
-// GetRestTime returns the current rest time in hundredths of seconds
-func (p *PM5) GetRestTime() (uint16, error) {
+// ============================================================================
+// PM5 Proprietary Get Data Commands
+// ============================================================================
+
+// GetPMWorkTime returns detailed work time in hundredths of seconds
+func (p *PM5) GetPMWorkTime() (uint32, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetRestTime)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetWorkTime)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetRestTime && len(cr.Data) >= 2 {
-			return uint16(cr.Data[0]) | uint16(cr.Data[1])<<8, nil
+		if cr.Command == csafe.PMCmdGetWorkTime && len(cr.Data) >= 4 {
+			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
+				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// GetErrorValue returns the last error value
-func (p *PM5) GetErrorValue() (uint16, error) {
+// GetPMWorkDistance returns the work distance in tenths of meters
+// Use GetPMWorkDistanceMeters for meters, matching WorkoutSnapshot.Distance
+func (p *PM5) GetPMWorkDistance() (uint32, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetErrorValue)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetWorkDistance)
 	if err != nil {
 		return 0, err
 	}
 
 	for _, cr := range resp.CommandData {
-		if cr.Command == csafe.PMCmdGetErrorValue && len(cr.Data) >= 2 {
-			return uint16(cr.Data[0])<<8 | uint16(cr.Data[1]), nil
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetWorkDistance && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
 		}
 	}
 
 	return 0, ErrInvalidResponse
 }
 
-// ============================================================================
-// Heart Rate Monitor Detection
-// ============================================================================
-
+// GetPMWorkDistanceMeters returns the work distance in meters
+func (p *PM5) GetPMWorkDistanceMeters() (float64, error) {
+	tenths, err := p.GetPMWorkDistance()
+	if err != nil {
+		return 0, err
+	}
+	return TenthsToMeters(tenths), nil
+}
+
+// GetStroke500mPace returns the current pace per 500m in hundredths of seconds
+func (p *PM5) GetStroke500mPace() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStroke500mPace)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetStroke500mPace && len(cr.Data) >= 4 {
+			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
+				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetStrokePower returns the current stroke power in watts
+func (p *PM5) GetStrokePower() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStrokePower)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetStrokePower && len(cr.Data) >= 4 {
+			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
+				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetStrokeCaloricBurnRate returns the stroke caloric burn rate in cals/hr
+func (p *PM5) GetStrokeCaloricBurnRate() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStrokeCaloricBurnRate)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetStrokeCaloricBurnRate && len(cr.Data) >= 4 {
+			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
+				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetStrokeRate returns the current stroke rate (strokes per minute)
+func (p *PM5) GetStrokeRate() (byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStrokeRate)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetStrokeRate && len(cr.Data) >= 1 {
+			return cr.Data[0], nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetDragFactor returns the current drag factor
+func (p *PM5) GetDragFactor() (byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetDragFactor)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetDragFactor && len(cr.Data) >= 1 {
+			return cr.Data[0], nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetTotalAvg500mPace returns the total average pace per 500m
+func (p *PM5) GetTotalAvg500mPace() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetTotalAvg500mPace)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetTotalAvg500mPace && len(cr.Data) >= 4 {
+			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
+				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetTotalAvgPower returns the total average power in watts
+func (p *PM5) GetTotalAvgPower() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetTotalAvgPower)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetTotalAvgPower && len(cr.Data) >= 4 {
+			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
+				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetTotalAvgCalories returns the total calories burned
+func (p *PM5) GetTotalAvgCalories() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetTotalAvgCalories)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetTotalAvgCalories && len(cr.Data) >= 4 {
+			return uint32(cr.Data[0])<<24 | uint32(cr.Data[1])<<16 |
+				uint32(cr.Data[2])<<8 | uint32(cr.Data[3]), nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetAvgHeartRate returns the average heart rate
+func (p *PM5) GetAvgHeartRate() (byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetAvgHeartRate)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if cr.Command == csafe.PMCmdGetAvgHeartRate && len(cr.Data) >= 1 {
+			return cr.Data[0], nil
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// StrokeStats contains detailed stroke statistics
+// Wire layout is 16 bytes; unlike the other PM-specific values, the multi-byte
+// fields are little-endian (LSB first).
+type StrokeStats struct {
+	StrokeDistance    uint16 // Bytes 0-1, little-endian: 0.01m units
+	DriveTime         byte   // Byte 2: 0.01s units
+	RecoveryTime      uint16 // Bytes 3-4, little-endian: 0.01s units
+	StrokeLength      byte   // Byte 5: 0.01m units
+	DriveCounter      uint16 // Bytes 6-7, little-endian
+	PeakDriveForce    uint16 // Bytes 8-9, little-endian: 0.1 lbs
+	ImpulseDriveForce uint16 // Bytes 10-11, little-endian: 0.1 lbs
+	AvgDriveForce     uint16 // Bytes 12-13, little-endian: 0.1 lbs
+	WorkPerStroke     uint16 // Bytes 14-15, little-endian: 0.1 Joules
+
+	// Deprecated: Use DriveTime. DriveTIme holds the same value and will be
+	// removed in the next release.
+	DriveTIme byte `json:"-"`
+}
+
+// lbfToNewtons converts pounds-force to newtons
+const lbfToNewtons = 4.4482216152605
+
+// DriveDuration returns the drive time as a duration
+func (s *StrokeStats) DriveDuration() time.Duration {
+	return HundredthsToTime(uint32(s.DriveTime))
+}
+
+// PeakDriveForceNewtons returns the peak drive force in newtons
+func (s *StrokeStats) PeakDriveForceNewtons() float64 {
+	return float64(s.PeakDriveForce) / 10 * lbfToNewtons
+}
+
+// GetStrokeStats returns detailed stroke statistics
+func (p *PM5) GetStrokeStats() (*StrokeStats, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStrokeStats, 0x00)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetStrokeStats && len(pmResp.Data) >= 16 {
+				return decodeStrokeStats(pmResp.Data), nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// decodeStrokeStats parses the 16-byte stroke statistics payload
+func decodeStrokeStats(d []byte) *StrokeStats {
+	return &StrokeStats{
+		StrokeDistance:    BytesToUint16(d[0:2]),
+		DriveTime:         d[2],
+		DriveTIme:         d[2],
+		RecoveryTime:      BytesToUint16(d[3:5]),
+		StrokeLength:      d[5],
+		DriveCounter:      BytesToUint16(d[6:8]),
+		PeakDriveForce:    BytesToUint16(d[8:10]),
+		ImpulseDriveForce: BytesToUint16(d[10:12]),
+		AvgDriveForce:     BytesToUint16(d[12:14]),
+		WorkPerStroke:     BytesToUint16(d[14:16]),
+	}
+}
+
+// MaxMemoryBlockLength is the most bytes GetMemory can read in one request
+const MaxMemoryBlockLength = 64
+
+// GetMemory reads a block of PM memory for low-level diagnostics
+// Request: Bytes 0-3 start address (big-endian), Byte 4 block length, at most
+// MaxMemoryBlockLength. Response: Byte 0 bytes read, followed by the data.
+func (p *PM5) GetMemory(address uint32, length byte) ([]byte, error) {
+	if length == 0 || length > MaxMemoryBlockLength {
+		return nil, fmt.Errorf("memory block length %d out of range 1-%d", length, MaxMemoryBlockLength)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readMemoryBlock(csafe.PMCmdGetMemory, address, length)
+}
+
+// readMemoryBlock sends a block read command (GetMemory or a log memory read)
+// and returns the bytes the PM reports as read
+func (p *PM5) readMemoryBlock(cmd byte, address uint32, length byte) ([]byte, error) {
+	pmCmd := csafe.BuildCommand(cmd,
+		byte((address>>24)&0xFF),
+		byte((address>>16)&0xFF),
+		byte((address>>8)&0xFF),
+		byte(address&0xFF),
+		length)
+	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == cmd && len(pmResp.Data) >= 1 {
+				n := min(int(pmResp.Data[0]), len(pmResp.Data)-1)
+				return append([]byte(nil), pmResp.Data[1:1+n]...), nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// GetForcePlotData returns force curve data points
+// blockSize is the number of bytes to read (max 32, returns 16 words)
+func (p *PM5) GetForcePlotData(blockSize byte) ([]uint16, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readForcePlotBlock(context.Background(), blockSize)
+}
+
+// readForcePlotBlock reads one block of force plot words (caller must hold p.mu)
+// The reply comes back under the CmdSetUserCfg1 wrapper; byte 0 is the number
+// of valid data bytes that follow, as big-endian words.
+func (p *PM5) readForcePlotBlock(ctx context.Context, blockSize byte) ([]uint16, error) {
+	if blockSize > 32 {
+		blockSize = 32
+	}
+
+	contents := csafe.BuildPMCommand(csafe.CmdSetUserCfg1, csafe.BuildCommand(csafe.PMCmdGetForcePlotData, blockSize))
+	resp, err := p.sendCommandCtx(ctx, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetForcePlotData && len(pmResp.Data) >= 1 {
+				n := min(int(pmResp.Data[0]), len(pmResp.Data)-1) / 2
+				words := make([]uint16, n)
+				for i := range words {
+					words[i] = BytesToUint16BE(pmResp.Data[1+i*2 : 3+i*2])
+				}
+				return words, nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// GetHeartBeatData returns buffered heartbeat samples
+// blockSize is the number of bytes to read (max 32, returns up to 16 words).
+// Each word is assumed to be the PM's timestamp of a detected beat in
+// milliseconds, big-endian, wrapping at 65536; pass the result to RRIntervals
+// for beat-to-beat intervals.
+func (p *PM5) GetHeartBeatData(blockSize byte) ([]uint16, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if blockSize > 32 {
+		blockSize = 32
+	}
+
+	resp, err := p.sendPMGet(csafe.CmdSetUserCfg1, csafe.PMCmdGetHeartBeatData, blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetHeartBeatData && len(pmResp.Data) >= 1 {
+				// Byte 0 is the number of valid data bytes that follow
+				n := min(int(pmResp.Data[0]), len(pmResp.Data)-1) / 2
+				beats := make([]uint16, n)
+				for i := range beats {
+					beats[i] = BytesToUint16BE(pmResp.Data[1+i*2 : 3+i*2])
+				}
+				return beats, nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// RRIntervals returns the intervals in milliseconds between successive beat
+// timestamps from GetHeartBeatData
+// Timestamp wraparound is handled; repeated timestamps are skipped.
+func RRIntervals(beats []uint16) []uint16 {
+	if len(beats) < 2 {
+		return nil
+	}
+
+	intervals := make([]uint16, 0, len(beats)-1)
+	for i := 1; i < len(beats); i++ {
+		// uint16 subtraction wraps, which is exactly the timestamp rollover
+		if rr := beats[i] - beats[i-1]; rr != 0 {
+			intervals = append(intervals, rr)
+		}
+	}
+	return intervals
+}
+
+// UIEvent represents a button or menu event reported by the PM
+type UIEvent struct {
+	Source byte // Originating control (button/menu)
+	Type   byte // Event type (press, release, etc.)
+}
+
+// GetUIEvents returns the user interface events queued since the last read
+// Each event is reported as a [source, type] byte pair
+func (p *PM5) GetUIEvents() ([]UIEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetUIEvents, 0x00)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetUIEvents {
+				// Never read past the reported byte count, even if more data follows
+				n := min(int(pmResp.ByteCount), len(pmResp.Data))
+				events := make([]UIEvent, 0, n/2)
+				for i := 0; i+1 < n; i += 2 {
+					events = append(events, UIEvent{
+						Source: pmResp.Data[i],
+						Type:   pmResp.Data[i+1],
+					})
+				}
+				return events, nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// GetRestTime returns the current rest time in hundredths of seconds
+// The value is big-endian like the other PM-specific values (bytes 0B B8 are
+// 30.00s); see the byte order note above BytesToUint16BE.
+func (p *PM5) GetRestTime() (uint16, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetRestTime)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetRestTime {
+				if rest, ok := decodeRestTime(pmResp.Data); ok {
+					return rest, nil
+				}
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// decodeRestTime parses a GetRestTime response (big-endian hundredths of seconds)
+func decodeRestTime(data []byte) (uint16, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+	return BytesToUint16BE(data[:2]), true
+}
+
+// GetSplitTime returns the elapsed time of the in-progress split in hundredths of seconds
+func (p *PM5) GetSplitTime() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetSplitTime)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetSplitTime && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetLastSplitTime returns the time of the last completed split in hundredths of seconds
+// The value updates when the split boundary is crossed, so read it after the split count changes
+func (p *PM5) GetLastSplitTime() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetLastSplitTime)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetLastSplitTime && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetSplitDistance returns the distance of the in-progress split in tenths of meters
+func (p *PM5) GetSplitDistance() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetSplitDistance)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetSplitDistance && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetLastSplitDistance returns the distance of the last completed split in tenths of meters
+// The value updates when the split boundary is crossed, so read it after the split count changes
+func (p *PM5) GetLastSplitDistance() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetLastSplitDistance)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetLastSplitDistance && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetSplitAvgPace returns the average pace of the current split in hundredths of seconds per 500m
+func (p *PM5) GetSplitAvgPace() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetSplitAvg500mPace)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetSplitAvg500mPace && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetSplitAvgPower returns the average power of the current split in watts
+func (p *PM5) GetSplitAvgPower() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetSplitAvgPower)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetSplitAvgPower && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetSplitCalories returns the calories burned in the current split
+// For calorie-goal intervals this is the split's progress toward its goal.
+func (p *PM5) GetSplitCalories() (uint32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetSplitAvgCalories)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetSplitAvgCalories && len(pmResp.Data) >= 4 {
+				return BytesToUint32BE(pmResp.Data[:4]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// SplitRecord is a per-split summary suitable for lap export
+type SplitRecord struct {
+	Time     time.Duration
+	Distance float64 // Meters
+	AvgPace  uint32  // Hundredths of seconds per 500m
+	AvgPower uint32  // Watts
+	Calories uint32
+}
+
+// SplitRecordCSVHeader is the column header matching SplitRecord.CSVRow
+var SplitRecordCSVHeader = []string{"time", "distance_m", "avg_pace", "avg_power_w", "calories"}
+
+// CSVRow formats the record as CSV fields in SplitRecordCSVHeader order
+func (r *SplitRecord) CSVRow() []string {
+	return []string{
+		FormatTime(TimeToHundredths(r.Time)),
+		fmt.Sprintf("%.1f", r.Distance),
+		FormatPace(r.AvgPace),
+		fmt.Sprintf("%d", r.AvgPower),
+		fmt.Sprintf("%d", r.Calories),
+	}
+}
+
+// GetSplitRecord reads the time, distance, average pace, average power, and
+// calories of the current split in a single frame
+// Read it just before the split boundary (or when the workout ends) to capture a lap
+func (p *PM5) GetSplitRecord() (*SplitRecord, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmds := [][]byte{
+		csafe.BuildCommand(csafe.PMCmdGetSplitTime),
+		csafe.BuildCommand(csafe.PMCmdGetSplitDistance),
+		csafe.BuildCommand(csafe.PMCmdGetSplitAvg500mPace),
+		csafe.BuildCommand(csafe.PMCmdGetSplitAvgPower),
+		csafe.BuildCommand(csafe.PMCmdGetSplitAvgCalories),
+	}
+	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmds...)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &SplitRecord{}
+	found := 0
+	for _, cr := range resp.CommandData {
+		for _, pmResp := range cr.PMResponses {
+			if len(pmResp.Data) < 4 {
+				continue
+			}
+			v := BytesToUint32BE(pmResp.Data[:4])
+			switch pmResp.Command {
+			case csafe.PMCmdGetSplitTime:
+				record.Time = HundredthsToTime(v)
+			case csafe.PMCmdGetSplitDistance:
+				record.Distance = TenthsToMeters(v)
+			case csafe.PMCmdGetSplitAvg500mPace:
+				record.AvgPace = v
+			case csafe.PMCmdGetSplitAvgPower:
+				record.AvgPower = v
+			case csafe.PMCmdGetSplitAvgCalories:
+				record.Calories = v
+			default:
+				continue
+			}
+			found++
+		}
+	}
+
+	if found < len(pmCmds) {
+		return nil, ErrInvalidResponse
+	}
+	return record, nil
+}
+
+// GetTotalRestTime returns the rest time accumulated across all intervals
+func (p *PM5) GetTotalRestTime() (time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetTotalRestTime)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetTotalRestTime && len(pmResp.Data) >= 4 {
+				return HundredthsToTime(BytesToUint32BE(pmResp.Data[:4])), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetRestDistance returns the distance covered during the current rest interval in meters
+func (p *PM5) GetRestDistance() (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetRestDistance)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetRestDistance && len(pmResp.Data) >= 2 {
+				return float64(BytesToUint16BE(pmResp.Data[:2])), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetTotalRestDistance returns the distance accumulated across all rest intervals in meters
+func (p *PM5) GetTotalRestDistance() (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetTotalRestDistance)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetTotalRestDistance && len(pmResp.Data) >= 4 {
+				return float64(BytesToUint32BE(pmResp.Data[:4])), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetLastRestDistance returns the distance covered during the last rest interval in meters
+// Only non-zero for workouts with variable (dynamic) rest
+func (p *PM5) GetLastRestDistance() (float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetLastRestDistance)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetLastRestDistance && len(pmResp.Data) >= 2 {
+				return float64(BytesToUint16BE(pmResp.Data[:2])), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// IntervalSummary totals the work and rest of an interval workout
+type IntervalSummary struct {
+	WorkTime     time.Duration
+	RestTime     time.Duration
+	WorkDistance float64 // Meters
+	RestDistance float64 // Meters
+}
+
+// TotalDistance returns the work and rest distance combined
+func (s *IntervalSummary) TotalDistance() float64 {
+	return s.WorkDistance + s.RestDistance
+}
+
+// GetIntervalSummary reads the total work and rest time and distance in a single frame
+func (p *PM5) GetIntervalSummary() (*IntervalSummary, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmds := [][]byte{
+		csafe.BuildCommand(csafe.PMCmdGetWorkTime),
+		csafe.BuildCommand(csafe.PMCmdGetWorkDistance),
+		csafe.BuildCommand(csafe.PMCmdGetTotalRestTime),
+		csafe.BuildCommand(csafe.PMCmdGetTotalRestDistance),
+	}
+	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmds...)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &IntervalSummary{}
+	found := 0
+	for _, cr := range resp.CommandData {
+		for _, pmResp := range cr.PMResponses {
+			if len(pmResp.Data) < 4 {
+				continue
+			}
+			v := BytesToUint32BE(pmResp.Data[:4])
+			switch pmResp.Command {
+			case csafe.PMCmdGetWorkTime:
+				summary.WorkTime = HundredthsToTime(v)
+			case csafe.PMCmdGetWorkDistance:
+				summary.WorkDistance = TenthsToMeters(v)
+			case csafe.PMCmdGetTotalRestTime:
+				summary.RestTime = HundredthsToTime(v)
+			case csafe.PMCmdGetTotalRestDistance:
+				summary.RestDistance = float64(v)
+			default:
+				continue
+			}
+			found++
+		}
+	}
+
+	if found < len(pmCmds) {
+		return nil, ErrInvalidResponse
+	}
+	return summary, nil
+}
+
+// GetErrorValue returns the last error value
+func (p *PM5) GetErrorValue() (uint16, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetErrorValue)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetErrorValue && len(pmResp.Data) >= 2 {
+				return BytesToUint16BE(pmResp.Data[0:2]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetErrorType returns the type of the error logged by the PM, which is needed
+// to interpret the code from GetErrorValue
+func (p *PM5) GetErrorType() (csafe.ErrorType, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetErrorType)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetErrorType && len(pmResp.Data) >= 1 {
+				return csafe.ErrorType(pmResp.Data[0]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// PMError is an error logged by the PM
+type PMError struct {
+	Type    csafe.ErrorType
+	Value   uint16 // Big-endian error code
+	Cleared bool   // Whether the PM acknowledged a clear request
+}
+
+// Present reports whether the PM has an error logged
+func (e *PMError) Present() bool {
+	return e.Type != 0 || e.Value != 0
+}
+
+func (e *PMError) Error() string {
+	return fmt.Sprintf("PM error type %s, value %d", e.Type, e.Value)
+}
+
+// GetAndClearError reads the logged error type and value in a single frame and
+// then clears the log if the PM supports it
+// The PM5 CSAFE interface has no command to acknowledge or clear a logged error
+// (it persists until the PM is power-cycled), so clearing is currently a no-op
+// and Cleared is always false. Reset the PM if its state machine must be
+// returned to a clean state after a fault.
+func (p *PM5) GetAndClearError() (*PMError, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmds := [][]byte{
+		csafe.BuildCommand(csafe.PMCmdGetErrorType),
+		csafe.BuildCommand(csafe.PMCmdGetErrorValue),
+	}
+	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmds...)
+	if err != nil {
+		return nil, err
+	}
+
+	pmErr := &PMError{}
+	found := 0
+	for _, cr := range resp.CommandData {
+		for _, pmResp := range cr.PMResponses {
+			switch pmResp.Command {
+			case csafe.PMCmdGetErrorType:
+				if len(pmResp.Data) >= 1 {
+					pmErr.Type = csafe.ErrorType(pmResp.Data[0])
+					found++
+				}
+			case csafe.PMCmdGetErrorValue:
+				if len(pmResp.Data) >= 2 {
+					pmErr.Value = BytesToUint16BE(pmResp.Data[0:2])
+					found++
+				}
+			}
+		}
+	}
+
+	if found < len(pmCmds) {
+		return nil, ErrInvalidResponse
+	}
+	return pmErr, nil
+}
+
+// HealthStatus is the PM's self-reported status type and value
+// A status type of zero means no fault is being reported
+type HealthStatus struct {
+	Type  csafe.StatusType
+	Value uint16 // Big-endian status value
+}
+
+// Healthy reports whether the PM is not reporting a fault
+func (h *HealthStatus) Healthy() bool {
+	return h.Type == csafe.StatusTypeNone
+}
+
+// GetHealthStatus reads the status type and value in a single frame
+// Returns ErrUnsupported if the PM rejects the status commands
+func (p *PM5) GetHealthStatus() (*HealthStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmds := [][]byte{
+		csafe.BuildCommand(csafe.PMCmdGetStatusType),
+		csafe.BuildCommand(csafe.PMCmdGetStatusValue),
+	}
+	resp, err := p.sendPMCommand(csafe.CmdGetPMData, pmCmds...)
+	if errors.Is(err, ErrCommandRejected) {
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &HealthStatus{}
+	haveType := false
+	for _, cr := range resp.CommandData {
+		for _, pmResp := range cr.PMResponses {
+			switch pmResp.Command {
+			case csafe.PMCmdGetStatusType:
+				if len(pmResp.Data) >= 1 {
+					status.Type = csafe.StatusType(pmResp.Data[0])
+					haveType = true
+				}
+			case csafe.PMCmdGetStatusValue:
+				if len(pmResp.Data) >= 2 {
+					status.Value = BytesToUint16BE(pmResp.Data[0:2])
+				}
+			}
+		}
+	}
+
+	if !haveType {
+		return nil, ErrUnsupported
+	}
+	return status, nil
+}
+
+// GetStatusType returns the type of the status the PM is reporting
+func (p *PM5) GetStatusType() (csafe.StatusType, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStatusType)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetStatusType && len(pmResp.Data) >= 1 {
+				return csafe.StatusType(pmResp.Data[0]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetStatusValue returns the status value the PM is reporting, interpreted
+// according to GetStatusType
+func (p *PM5) GetStatusValue() (uint16, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetStatusValue)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetStatusValue && len(pmResp.Data) >= 2 {
+				return BytesToUint16BE(pmResp.Data[0:2]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// GetCurrentWorkoutHash returns the hash identifying the workout loaded on the PM
+// Compare the hash before and after programming a workout to confirm the PM
+// accepted it rather than falling back to Just Row.
+func (p *PM5) GetCurrentWorkoutHash() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetCurrentWorkoutHash)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetCurrentWorkoutHash && len(pmResp.Data) > 0 {
+				return append([]byte(nil), pmResp.Data...), nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// WorkoutHashString formats a workout hash as lowercase hex
+func WorkoutHashString(hash []byte) string {
+	return hex.EncodeToString(hash)
+}
+
+// ============================================================================
+// Heart Rate Monitor Detection
+// ============================================================================
+
 // HRMStatus represents the heart rate monitor connection status
 type HRMStatus struct {
 	Connected bool
@@ -669,8 +1878,7 @@ func (p *PM5) GetHRMStatus() (*HRMStatus, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmd := csafe.BuildCommand(csafe.PMCmdGetHRM)
-	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg, pmCmd)
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, csafe.PMCmdGetHRM)
 	if err != nil {
 		return nil, err
 	}
@@ -691,3 +1899,68 @@ func (p *PM5) GetHRMStatus() (*HRMStatus, error) {
 
 	return nil, ErrInvalidResponse
 }
+
+// HRBeltInfo identifies the heart rate belt paired with the PM
+// Wire layout: Byte 0 manufacturer ID, Byte 1 device type, then the belt ID
+// (big-endian, 2 bytes standard or 4 bytes extended)
+type HRBeltInfo struct {
+	ManufacturerID byte
+	DeviceType     byte
+	BeltID         uint32
+	Extended       bool   // Decoded from the extended belt info command
+	Raw            []byte // Complete response payload
+}
+
+// GetHRBeltInfo returns the identity of the paired heart rate belt
+// The extended variant is tried first, falling back to the standard one on
+// firmware that does not support it
+func (p *PM5) GetHRBeltInfo() (*HRBeltInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := p.getHRBeltInfo(csafe.PMCmdGetExtendedHRBeltInfo)
+	if err == nil {
+		return info, nil
+	}
+	if !errors.Is(err, ErrInvalidResponse) && !errors.Is(err, ErrCommandRejected) {
+		return nil, err
+	}
+	return p.getHRBeltInfo(csafe.PMCmdGetHRBeltInfo)
+}
+
+// getHRBeltInfo sends a single belt info command (caller must hold p.mu)
+func (p *PM5) getHRBeltInfo(cmd byte) (*HRBeltInfo, error) {
+	resp, err := p.sendPMGet(csafe.CmdGetPMCfg, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == cmd && len(pmResp.Data) >= 2 {
+				return decodeHRBeltInfo(pmResp.Data, cmd == csafe.PMCmdGetExtendedHRBeltInfo), nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// decodeHRBeltInfo parses a belt info payload, tolerating a short belt ID
+func decodeHRBeltInfo(data []byte, extended bool) *HRBeltInfo {
+	info := &HRBeltInfo{
+		ManufacturerID: data[0],
+		DeviceType:     data[1],
+		Extended:       extended,
+		Raw:            append([]byte(nil), data...),
+	}
+
+	idLen := 2
+	if extended {
+		idLen = 4
+	}
+	for _, b := range data[2:min(len(data), 2+idLen)] {
+		info.BeltID = info.BeltID<<8 | uint32(b)
+	}
+	return info
+}
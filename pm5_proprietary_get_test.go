@@ -0,0 +1,506 @@
+package pm5
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+func TestIsWorkoutLoggable(t *testing.T) {
+	logParams := cmdData(csafe.PMCmdGetInternalLogParams, 0x00, 0x00, 0x10, 0x00, 0x00, 0x05)
+
+	tests := []struct {
+		name     string
+		workout  csafe.WorkoutType
+		state    csafe.WorkoutState
+		withLog  bool
+		loggable bool
+	}{
+		{"fixed distance in progress", csafe.WorkoutTypeFixedDistSplits, csafe.WorkoutStateWorkoutRow, true, true},
+		{"just row", csafe.WorkoutTypeJustRowNoSplits, csafe.WorkoutStateWorkoutRow, true, false},
+		{"just row already logged", csafe.WorkoutTypeJustRowNoSplits, csafe.WorkoutStateWorkoutLogged, true, true},
+		{"no internal log", csafe.WorkoutTypeFixedDistSplits, csafe.WorkoutStateWorkoutRow, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, dev, _ := newTestPM5(t)
+			cmds := [][]byte{
+				cmdData(csafe.PMCmdGetWorkoutType, byte(tt.workout)),
+				cmdData(csafe.PMCmdGetWorkoutState, byte(tt.state)),
+			}
+			if tt.withLog {
+				cmds = append(cmds, logParams)
+			}
+			queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg, cmds...))
+
+			got, err := p.IsWorkoutLoggable()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.loggable {
+				t.Errorf("IsWorkoutLoggable() = %v, want %v", got, tt.loggable)
+			}
+		})
+	}
+}
+
+func TestGetProductConfigurationRaw(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	payload := []byte{0x00, 0x05, 0x01, 0x2C, 0x00, 0x03, 0xF1}
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+		cmdData(csafe.PMCmdGetProductConfiguration, payload...)))
+
+	got, err := p.GetProductConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("GetProductConfiguration() = % X, want % X", got, payload)
+	}
+
+	// The undecoded block must not be taken as the machine type
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+		cmdData(csafe.PMCmdGetErgMachineType, byte(csafe.ErgMachineTypeBike))))
+	machine, err := p.GetErgMachineType()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if machine != csafe.ErgMachineTypeBike {
+		t.Errorf("GetErgMachineType() = %v, want BikeErg", machine)
+	}
+}
+
+func TestGetIntervalProgressBoundary(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   byte
+		program int // intervals programmed via SetWorkoutIntervalCount, 0 for none
+		current int
+		total   int
+	}{
+		{"first interval", 0x00, 0, 1, 0},
+		{"last byte value", 0xFF, 0, 256, 0},
+		{"programmed 256 intervals", 0xFF, 256, 256, 256},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, dev, _ := newTestPM5(t)
+			if tt.program > 0 {
+				queueResponse(t, dev, statusReady)
+				if err := p.SetWorkoutIntervalCount(byte(tt.program - 1)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+				cmdData(csafe.PMCmdGetWorkoutIntervalCount, tt.reply)))
+
+			current, total, err := p.GetIntervalProgress()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current != tt.current || total != tt.total {
+				t.Errorf("GetIntervalProgress() = %d of %d, want %d of %d", current, total, tt.current, tt.total)
+			}
+		})
+	}
+}
+
+func TestIsActivelyRowing(t *testing.T) {
+	tests := []struct {
+		name   string
+		rowing csafe.RowingState
+		stroke csafe.StrokeState
+		rate   byte
+		want   bool
+	}{
+		{"active drive", csafe.RowingStateActive, csafe.StrokeStateDriving, 24, true},
+		{"active recovery before the rate settles", csafe.RowingStateActive, csafe.StrokeStateRecovery, 0, true},
+		{"catch with rowing state dropped", csafe.RowingStateInactive, csafe.StrokeStateWaitingForWheelToAccelerate, 24, true},
+		{"drive with rowing state dropped", csafe.RowingStateInactive, csafe.StrokeStateDriving, 22, true},
+		{"paused with a stale rate", csafe.RowingStateInactive, csafe.StrokeStateWaitingForWheelToReachMinSpeed, 24, false},
+		{"stopped during recovery", csafe.RowingStateInactive, csafe.StrokeStateRecovery, 0, false},
+		{"idle", csafe.RowingStateInactive, csafe.StrokeStateWaitingForWheelToReachMinSpeed, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, dev, _ := newTestPM5(t)
+			queueResponse(t, dev, statusReady,
+				pmData(csafe.CmdGetPMCfg, cmdData(csafe.PMCmdGetRowingState, byte(tt.rowing))),
+				pmData(csafe.CmdGetPMData,
+					cmdData(csafe.PMCmdGetStrokeState, byte(tt.stroke)),
+					cmdData(csafe.PMCmdGetStrokeRate, tt.rate)))
+
+			rowing, err := p.IsActivelyRowing()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rowing != tt.want {
+				t.Errorf("IsActivelyRowing() = %v, want %v", rowing, tt.want)
+			}
+
+			frames := decodeWritten(t, dev)
+			if len(frames) != 1 {
+				t.Fatalf("wrote %d frames, want 1", len(frames))
+			}
+			want := []byte{
+				csafe.CmdGetPMCfg, 0x01, csafe.PMCmdGetRowingState,
+				csafe.CmdGetPMData, 0x02, csafe.PMCmdGetStrokeState, csafe.PMCmdGetStrokeRate,
+			}
+			if !bytes.Equal(frames[0].Contents, want) {
+				t.Errorf("request = % X, want % X", frames[0].Contents, want)
+			}
+		})
+	}
+
+	// Missing indicators are not read as "not rowing"
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady,
+		pmData(csafe.CmdGetPMCfg, cmdData(csafe.PMCmdGetRowingState, byte(csafe.RowingStateInactive))))
+	if _, err := p.IsActivelyRowing(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("IsActivelyRowing() partial response error = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestGetForcePlotData(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdSetUserCfg1,
+		cmdData(csafe.PMCmdGetForcePlotData, 4, 0x00, 0x0A, 0x00, 0x14)))
+
+	words, err := p.GetForcePlotData(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{10, 20}; !slices.Equal(words, want) {
+		t.Errorf("GetForcePlotData() = %v, want %v", words, want)
+	}
+
+	// The block size is capped at 32 bytes
+	frames := decodeWritten(t, dev)
+	if len(frames) != 1 {
+		t.Fatalf("wrote %d frames, want 1", len(frames))
+	}
+	want := []byte{csafe.CmdSetUserCfg1, 0x03, csafe.PMCmdGetForcePlotData, 0x01, 32}
+	if !bytes.Equal(frames[0].Contents, want) {
+		t.Errorf("request = % X, want % X", frames[0].Contents, want)
+	}
+
+	// An empty block is an empty curve, not an error
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdSetUserCfg1,
+		cmdData(csafe.PMCmdGetForcePlotData, 0)))
+	words, err = p.GetForcePlotData(32)
+	if err != nil || len(words) != 0 {
+		t.Errorf("GetForcePlotData() empty block = %v, %v, want no words", words, err)
+	}
+}
+
+func TestGetHeartBeatDataRRIntervals(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	// Five big-endian millisecond timestamps: a repeat, then a wrap past 65535
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdSetUserCfg1,
+		cmdData(csafe.PMCmdGetHeartBeatData, 10,
+			0xFA, 0x00, // 64000
+			0xFD, 0x34, // 64820
+			0xFD, 0x34, // 64820 again
+			0x00, 0x64, // 100, after the wrap
+			0x03, 0xB6))) // 950
+
+	beats, err := p.GetHeartBeatData(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{64000, 64820, 64820, 100, 950}; !slices.Equal(beats, want) {
+		t.Errorf("GetHeartBeatData() = %v, want %v", beats, want)
+	}
+	if want := []uint16{820, 816, 850}; !slices.Equal(RRIntervals(beats), want) {
+		t.Errorf("RRIntervals() = %v, want %v", RRIntervals(beats), want)
+	}
+
+	// The block size is capped at 32 bytes
+	frames := decodeWritten(t, dev)
+	want := []byte{csafe.CmdSetUserCfg1, 0x03, csafe.PMCmdGetHeartBeatData, 0x01, 32}
+	if len(frames) != 1 {
+		t.Fatalf("wrote %d frames, want 1", len(frames))
+	}
+	if !bytes.Equal(frames[0].Contents, want) {
+		t.Errorf("request = % X, want % X", frames[0].Contents, want)
+	}
+
+	if got := RRIntervals([]uint16{500}); got != nil {
+		t.Errorf("RRIntervals(one beat) = %v, want nil", got)
+	}
+}
+
+func TestGetRestTime(t *testing.T) {
+	// 0B B8 is 3000 (30.00s) read MSB first; LSB first it would be 47115
+	tests := []struct {
+		data []byte
+		want uint16
+		ok   bool
+	}{
+		{[]byte{0x0B, 0xB8}, 3000, true},
+		{[]byte{0x00, 0x64, 0xFF}, 100, true},
+		{[]byte{0x0B}, 0, false},
+		{nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := decodeRestTime(tt.data)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("decodeRestTime(% X) = %d, %v, want %d, %v", tt.data, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetRestTime, 0x0B, 0xB8)))
+	rest, err := p.GetRestTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest != 3000 {
+		t.Errorf("GetRestTime() = %d, want 3000", rest)
+	}
+
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetRestTime, 0x0B)))
+	if _, err := p.GetRestTime(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("GetRestTime() short payload error = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestGetLastRestDistance(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetLastRestDistance, 0x01, 0xF4)))
+
+	got, err := p.GetLastRestDistance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 500 {
+		t.Errorf("GetLastRestDistance() = %v, want 500", got)
+	}
+}
+
+func TestGetScreenStateCSAFE(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMCfg,
+		cmdData(csafe.PMCmdGetScreenStateStatus,
+			byte(csafe.ScreenTypeCSAFE), 0x02, byte(csafe.ScreenStatusPending), 0x05)))
+
+	state, err := p.GetScreenState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ScreenState{
+		Type:         csafe.ScreenTypeCSAFE,
+		Value:        0x02,
+		Status:       csafe.ScreenStatusPending,
+		SubStatus:    0x05,
+		HasSubStatus: true,
+	}
+	if *state != want {
+		t.Errorf("GetScreenState() = %+v, want %+v", *state, want)
+	}
+	if !state.Pending() {
+		t.Error("Pending() = false, want true")
+	}
+}
+
+func TestDecodeScreenStateSubStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want ScreenState
+	}{
+		{"CSAFE without sub-state", []byte{byte(csafe.ScreenTypeCSAFE), 0x01, 0x00},
+			ScreenState{Type: csafe.ScreenTypeCSAFE, Value: 0x01}},
+		{"other screens ignore byte 3", []byte{byte(csafe.ScreenTypeWorkout), 0x01, 0x00, 0x07},
+			ScreenState{Type: csafe.ScreenTypeWorkout, Value: 0x01}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeScreenState(tt.data); *got != tt.want {
+				t.Errorf("decodeScreenState(% X) = %+v, want %+v", tt.data, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSplitAverages(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetSplitAvg500mPace, 0x00, 0x00, 0x2E, 0xE0)))
+	pace, err := p.GetSplitAvgPace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pace != 12000 {
+		t.Errorf("GetSplitAvgPace() = %d, want 12000", pace)
+	}
+
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetSplitAvgPower, 0x00, 0x00, 0x00, 0xCB)))
+	power, err := p.GetSplitAvgPower()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if power != 203 {
+		t.Errorf("GetSplitAvgPower() = %d, want 203", power)
+	}
+}
+
+func TestGetSplitCalories(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetSplitAvgCalories, 0x00, 0x00, 0x01, 0x2C)))
+	cals, err := p.GetSplitCalories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cals != 300 {
+		t.Errorf("GetSplitCalories() = %d, want 300", cals)
+	}
+
+	// A short payload is not a calorie total
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetSplitAvgCalories, 0x01, 0x2C)))
+	if _, err := p.GetSplitCalories(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("GetSplitCalories() short payload error = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestGetSplitRecord(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetSplitTime, 0x00, 0x00, 0x2E, 0xE0),
+		cmdData(csafe.PMCmdGetSplitDistance, 0x00, 0x00, 0x13, 0x88),
+		cmdData(csafe.PMCmdGetSplitAvg500mPace, 0x00, 0x00, 0x2E, 0xE0),
+		cmdData(csafe.PMCmdGetSplitAvgPower, 0x00, 0x00, 0x00, 0xCB),
+		cmdData(csafe.PMCmdGetSplitAvgCalories, 0x00, 0x00, 0x00, 0x19)))
+
+	record, err := p.GetSplitRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SplitRecord{Time: 2 * time.Minute, Distance: 500, AvgPace: 12000, AvgPower: 203, Calories: 25}
+	if *record != want {
+		t.Errorf("GetSplitRecord() = %+v, want %+v", *record, want)
+	}
+
+	row := record.CSVRow()
+	if len(row) != len(SplitRecordCSVHeader) {
+		t.Fatalf("CSVRow() has %d fields, header has %d", len(row), len(SplitRecordCSVHeader))
+	}
+	wantRow := []string{"2:00.00", "500.0", "2:00.0", "203", "25"}
+	if !slices.Equal(row, wantRow) {
+		t.Errorf("CSVRow() = %q, want %q", row, wantRow)
+	}
+
+	// A split missing any of its values is not a complete lap
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetSplitTime, 0x00, 0x00, 0x2E, 0xE0)))
+	if _, err := p.GetSplitRecord(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("GetSplitRecord() with a partial response error = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestGetTotalRestDistance(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetTotalRestDistance, 0x00, 0x00, 0x02, 0x58)))
+
+	meters, err := p.GetTotalRestDistance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meters != 600 {
+		t.Errorf("GetTotalRestDistance() = %v, want 600", meters)
+	}
+}
+
+func TestGetIntervalSummary(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetWorkTime, 0x00, 0x00, 0x5D, 0xC0),
+		cmdData(csafe.PMCmdGetWorkDistance, 0x00, 0x00, 0x9C, 0x40),
+		cmdData(csafe.PMCmdGetTotalRestTime, 0x00, 0x00, 0x2E, 0xE0),
+		cmdData(csafe.PMCmdGetTotalRestDistance, 0x00, 0x00, 0x01, 0x2C)))
+
+	summary, err := p.GetIntervalSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Work distance is reported in tenths of a meter, rest distance in meters
+	want := IntervalSummary{
+		WorkTime:     4 * time.Minute,
+		RestTime:     2 * time.Minute,
+		WorkDistance: 4000,
+		RestDistance: 300,
+	}
+	if *summary != want {
+		t.Errorf("GetIntervalSummary() = %+v, want %+v", *summary, want)
+	}
+	if got := summary.TotalDistance(); got != 4300 {
+		t.Errorf("TotalDistance() = %v, want 4300", got)
+	}
+
+	// All four values come from one frame
+	if frames := decodeWritten(t, dev); len(frames) != 1 {
+		t.Errorf("sent %d frames, want 1", len(frames))
+	}
+
+	// A summary missing the rest distance is incomplete
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetWorkTime, 0x00, 0x00, 0x5D, 0xC0),
+		cmdData(csafe.PMCmdGetWorkDistance, 0x00, 0x00, 0x9C, 0x40),
+		cmdData(csafe.PMCmdGetTotalRestTime, 0x00, 0x00, 0x2E, 0xE0)))
+	if _, err := p.GetIntervalSummary(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("GetIntervalSummary() missing a value error = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestGetAndClearError(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetErrorType, 0x03),
+		cmdData(csafe.PMCmdGetErrorValue, 0x01, 0x2C)))
+
+	pmErr, err := p.GetAndClearError()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PMError{Type: csafe.ErrorType(3), Value: 300}
+	if *pmErr != want {
+		t.Errorf("GetAndClearError() = %+v, want %+v", *pmErr, want)
+	}
+	if !pmErr.Present() {
+		t.Error("Present() = false for a logged error")
+	}
+
+	// Both values are read in one frame; clearing is unsupported, so nothing
+	// further is sent
+	frames := decodeWritten(t, dev)
+	if len(frames) != 1 {
+		t.Fatalf("sent %d frames, want 1", len(frames))
+	}
+	wantContents := []byte{csafe.CmdGetPMData, 0x02, csafe.PMCmdGetErrorType, csafe.PMCmdGetErrorValue}
+	if !bytes.Equal(frames[0].Contents, wantContents) {
+		t.Errorf("request = % X, want % X", frames[0].Contents, wantContents)
+	}
+
+	// Half an error is not an error report
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetErrorType, 0x03)))
+	if _, err := p.GetAndClearError(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("GetAndClearError() missing value error = %v, want ErrInvalidResponse", err)
+	}
+}
@@ -1,6 +1,9 @@
 package pm5
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/danhigham/pm5/csafe"
 )
 
@@ -15,9 +18,50 @@ func (p *PM5) SetWorkoutType(workoutType csafe.WorkoutType) error {
 
 	pmCmd := csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(workoutType))
 	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd)
+	if err == nil {
+		p.setWorkoutType(workoutType)
+	}
 	return err
 }
 
+// setWorkoutType records the programmed workout type; the caller must hold p.mu
+// A new workout type also forgets the interval total of the previous workout
+func (p *PM5) setWorkoutType(workoutType csafe.WorkoutType) {
+	p.workoutType = workoutType
+	p.workoutKnown = true
+	p.intervalTotal = 0
+}
+
+// ValidateSplitDuration checks that a split duration type makes sense for a workout type
+// e.g. a fixed distance workout only accepts distance splits
+func ValidateSplitDuration(workoutType csafe.WorkoutType, durationType csafe.DurationType) error {
+	var allowed []csafe.DurationType
+	switch workoutType {
+	case csafe.WorkoutTypeJustRowNoSplits, csafe.WorkoutTypeJustRowSplits:
+		allowed = []csafe.DurationType{csafe.DurationTypeTime, csafe.DurationTypeDistance}
+	case csafe.WorkoutTypeFixedDistNoSplits, csafe.WorkoutTypeFixedDistSplits,
+		csafe.WorkoutTypeFixedDistInterval:
+		allowed = []csafe.DurationType{csafe.DurationTypeDistance}
+	case csafe.WorkoutTypeFixedTimeNoSplits, csafe.WorkoutTypeFixedTimeSplits,
+		csafe.WorkoutTypeFixedTimeInterval:
+		allowed = []csafe.DurationType{csafe.DurationTypeTime}
+	case csafe.WorkoutTypeFixedCalorieSplits, csafe.WorkoutTypeFixedCalsInterval:
+		allowed = []csafe.DurationType{csafe.DurationTypeCalories}
+	case csafe.WorkoutTypeFixedWattMinuteSplits:
+		allowed = []csafe.DurationType{csafe.DurationTypeWattMin}
+	default:
+		// Variable intervals mix duration types
+		return nil
+	}
+
+	for _, t := range allowed {
+		if t == durationType {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s split is not valid for a %s workout", durationType, workoutType)
+}
+
 // SetWorkoutDuration sets the workout duration
 // durationType specifies Time (0x00), Calories (0x40), Distance (0x80), or WattMin (0xC0)
 // duration is in appropriate units: 0.01s for time, meters for distance, cals, or watt-min
@@ -49,10 +93,17 @@ func (p *PM5) SetRestDuration(seconds uint16) error {
 
 // SetSplitDuration sets the split duration
 // durationType specifies Time (0x00), Calories (0x40), Distance (0x80), or WattMin (0xC0)
+// If the workout type was set through this PM5, the split type is validated against it
 func (p *PM5) SetSplitDuration(durationType csafe.DurationType, duration uint32) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.workoutKnown {
+		if err := ValidateSplitDuration(p.workoutType, durationType); err != nil {
+			return err
+		}
+	}
+
 	pmCmd := csafe.BuildCommand(csafe.PMCmdSetSplitDuration,
 		byte(durationType),
 		byte((duration>>24)&0xFF),
@@ -87,13 +138,18 @@ func (p *PM5) SetIntervalType(intervalType csafe.IntervalType) error {
 	return err
 }
 
-// SetWorkoutIntervalCount sets the current interval number (1-indexed)
+// SetWorkoutIntervalCount sets the interval being programmed (0-indexed, as
+// reported by GetWorkoutIntervalCount)
+// The highest interval programmed is remembered as the total for GetIntervalProgress
 func (p *PM5) SetWorkoutIntervalCount(count byte) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	pmCmd := csafe.BuildCommand(csafe.PMCmdSetWorkoutIntervalCount, count)
 	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd)
+	if err == nil && int(count) >= p.intervalTotal {
+		p.intervalTotal = int(count) + 1
+	}
 	return err
 }
 
@@ -147,6 +203,32 @@ func (p *PM5) SetScreenState(screenType csafe.ScreenType, screenValue byte) erro
 	return err
 }
 
+// SetDisplayFormat switches the workout screen layout
+// Only the Standard, ForceCurve, PaceBoat, and Target formats can be selected remotely
+func (p *PM5) SetDisplayFormat(format csafe.DisplayFormatType) error {
+	var screenValue csafe.ScreenValueWorkout
+	switch format {
+	case csafe.DisplayTypeStandard:
+		screenValue = csafe.ScreenValueWorkoutChangeDisplayTypeStandard
+	case csafe.DisplayTypeForceCurve:
+		screenValue = csafe.ScreenValueWorkoutChangeDisplayTypeForceCurve
+	case csafe.DisplayTypePaceBoat:
+		screenValue = csafe.ScreenValueWorkoutChangeDisplayTypePaceBoat
+	case csafe.DisplayTypeTarget:
+		screenValue = csafe.ScreenValueWorkoutChangeDisplayTypeTarget
+	default:
+		return fmt.Errorf("display format %d cannot be set remotely", format)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetScreenState,
+		byte(csafe.ScreenTypeWorkout), byte(screenValue))
+	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd)
+	return err
+}
+
 // SetScreenErrorMode enables or disables screen error display mode
 func (p *PM5) SetScreenErrorMode(enable bool) error {
 	p.mu.Lock()
@@ -173,6 +255,65 @@ func (p *PM5) SetDisplayUpdateRate(rate byte) error {
 	return err
 }
 
+// MaxDisplayStringLength is the maximum number of characters SetDisplayString sends
+const MaxDisplayStringLength = 32
+
+// SetDisplayString writes custom text to the PM display
+// Strings longer than MaxDisplayStringLength are truncated rather than rejected,
+// and shorter strings are padded with spaces so previous text is overwritten.
+// Only printable ASCII is supported; any other character returns an error.
+func (p *PM5) SetDisplayString(s string) error {
+	text := make([]byte, MaxDisplayStringLength)
+	for i := range text {
+		text[i] = ' '
+	}
+
+	n := 0
+	for i, r := range s {
+		if r < 0x20 || r > 0x7E {
+			return fmt.Errorf("display string has non-ASCII character %q at byte %d", r, i)
+		}
+		if n < MaxDisplayStringLength {
+			text[n] = byte(r)
+			n++
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetDisplayString, text...)
+	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd)
+	return err
+}
+
+// SetUserProfile stores the user profile used for calorie calculations
+// Weight is encoded in 0.1 kg units; see UserProfile for the byte layout
+func (p *PM5) SetUserProfile(profile *UserProfile) error {
+	if profile == nil {
+		return fmt.Errorf("user profile is nil")
+	}
+	if profile.WeightKg <= 0 || profile.WeightKg*10 > 0xFFFF {
+		return fmt.Errorf("weight %.1f kg out of range", profile.WeightKg)
+	}
+	if profile.Gender != csafe.GenderMale && profile.Gender != csafe.GenderFemale {
+		return fmt.Errorf("invalid gender %d", profile.Gender)
+	}
+
+	weight := uint16(profile.WeightKg*10 + 0.5)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetUserProfile,
+		profile.Age,
+		byte((weight>>8)&0xFF),
+		byte(weight&0xFF),
+		byte(profile.Gender))
+	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd)
+	return err
+}
+
 // DateTime represents date and time for the PM
 type DateTime struct {
 	Hours    byte // 1-12
@@ -200,204 +341,367 @@ func (p *PM5) SetDateTime(dt *DateTime) error {
 	return err
 }
 
-// ============================================================================
-// Workout Setup Helpers
-// ============================================================================
-
-// StartJustRowWorkout starts a simple "Just Row" workout with optional splits
-func (p *PM5) StartJustRowWorkout(withSplits bool) error {
-	workoutType := csafe.WorkoutTypeJustRowNoSplits
-	if withSplits {
-		workoutType = csafe.WorkoutTypeJustRowSplits
-	}
+// HRMUnpaired is the belt ID that clears the PM's heart rate belt pairing
+const HRMUnpaired = 0
 
-	// Build combined command
+// SetHRM pairs the PM with the heart rate belt with the given ID
+// The belt ID is sent big-endian (high byte first), matching HRBeltInfo.BeltID
+func (p *PM5) SetHRM(beltID uint16) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmds := [][]byte{
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(workoutType)),
-		csafe.BuildCommand(csafe.PMCmdSetScreenState,
-			byte(csafe.ScreenTypeWorkout),
-			byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)),
-	}
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetHRM,
+		byte((beltID>>8)&0xFF),
+		byte(beltID&0xFF))
+	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd)
+	return err
+}
+
+// SetExtendedHRM pairs the PM with a belt using a 32-bit extended belt ID
+// The belt ID is sent big-endian (high byte first)
+func (p *PM5) SetExtendedHRM(beltID uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...)
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetExtendedHRM,
+		byte((beltID>>24)&0xFF),
+		byte((beltID>>16)&0xFF),
+		byte((beltID>>8)&0xFF),
+		byte(beltID&0xFF))
+	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd)
 	return err
 }
 
-// StartFixedDistanceWorkout starts a fixed distance workout
-// distance is in meters, splitDistance is in meters (0 for no splits)
-func (p *PM5) StartFixedDistanceWorkout(distance uint32, splitDistance uint32) error {
-	workoutType := csafe.WorkoutTypeFixedDistNoSplits
-	if splitDistance > 0 {
-		workoutType = csafe.WorkoutTypeFixedDistSplits
-	}
+// ClearHRM unpairs the current heart rate belt so the PM can pair with another
+func (p *PM5) ClearHRM() error {
+	return p.SetHRM(HRMUnpaired)
+}
 
+// ResetAll restores the PM configuration to factory defaults
+// This is destructive: the user profile, heart rate belt pairing, display
+// settings, and any programmed workout are cleared. The logbook of completed
+// workouts is not affected.
+func (p *PM5) ResetAll() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmds := [][]byte{
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(workoutType)),
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutDuration,
-			byte(csafe.DurationTypeDistance),
-			byte((distance>>24)&0xFF),
-			byte((distance>>16)&0xFF),
-			byte((distance>>8)&0xFF),
-			byte(distance&0xFF)),
-	}
-
-	if splitDistance > 0 {
-		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetSplitDuration,
-			byte(csafe.DurationTypeDistance),
-			byte((splitDistance>>24)&0xFF),
-			byte((splitDistance>>16)&0xFF),
-			byte((splitDistance>>8)&0xFF),
-			byte(splitDistance&0xFF)))
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetResetAll)
+	if _, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmd); err != nil {
+		return err
 	}
+	p.workoutKnown = false
+	p.intervalTotal = 0
+	return nil
+}
 
-	pmCmds = append(pmCmds,
-		csafe.BuildCommand(csafe.PMCmdConfigureWorkout, 0x01), // Enable
-		csafe.BuildCommand(csafe.PMCmdSetScreenState,
-			byte(csafe.ScreenTypeWorkout),
-			byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)))
+// ============================================================================
+// Workout Setup Helpers
+// ============================================================================
 
-	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...)
-	return err
+// WorkoutSpec describes a workout for SetWorkoutFromSpec
+// Units follow SetWorkoutDuration: 0.01s for time, meters for distance,
+// calories, or watt-minutes, as selected by DurationType.
+type WorkoutSpec struct {
+	Type          csafe.WorkoutType
+	Duration      uint32             // Workout (or interval) goal; 0 for Just Row
+	DurationType  csafe.DurationType // Unit of Duration and SplitDuration
+	SplitDuration uint32             // Split length for split workout types (0 = PM default)
+	Rest          uint16             // Rest in seconds, interval workouts only
+	TargetPace    uint32             // Pace boat target in 0.01s per 500m (0 = none)
+	IntervalType  csafe.IntervalType // Variable interval workouts only
+	IntervalCount byte               // Interval being programmed (0-indexed), variable interval workouts only
 }
 
-// StartFixedTimeWorkout starts a fixed time workout
-// duration is in hundredths of seconds, splitDuration is in hundredths of seconds (0 for no splits)
-func (p *PM5) StartFixedTimeWorkout(duration uint32, splitDuration uint32) error {
-	workoutType := csafe.WorkoutTypeFixedTimeNoSplits
-	if splitDuration > 0 {
-		workoutType = csafe.WorkoutTypeFixedTimeSplits
+// workoutTypeTraits classifies a workout type for WorkoutSpec validation
+func workoutTypeTraits(t csafe.WorkoutType) (justRow, splits, interval, variable bool) {
+	switch t {
+	case csafe.WorkoutTypeJustRowNoSplits:
+		justRow = true
+	case csafe.WorkoutTypeJustRowSplits:
+		justRow, splits = true, true
+	case csafe.WorkoutTypeFixedDistSplits, csafe.WorkoutTypeFixedTimeSplits,
+		csafe.WorkoutTypeFixedCalorieSplits, csafe.WorkoutTypeFixedWattMinuteSplits:
+		splits = true
+	case csafe.WorkoutTypeFixedTimeInterval, csafe.WorkoutTypeFixedDistInterval,
+		csafe.WorkoutTypeFixedCalsInterval:
+		interval = true
+	case csafe.WorkoutTypeVariableInterval, csafe.WorkoutTypeVariableUndefinedRestInterval:
+		interval, variable = true, true
 	}
+	return
+}
 
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// Validate checks that the fields of the spec make sense together
+func (spec WorkoutSpec) Validate() error {
+	_, err := spec.commands()
+	return err
+}
 
-	pmCmds := [][]byte{
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(workoutType)),
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutDuration,
-			byte(csafe.DurationTypeTime),
-			byte((duration>>24)&0xFF),
-			byte((duration>>16)&0xFF),
-			byte((duration>>8)&0xFF),
-			byte(duration&0xFF)),
+// commands validates the spec and builds its PM command batch in the order the
+// PM expects: interval count, workout type, interval type, duration, split,
+// rest, target pace, then configure and switch to the workout screen
+func (spec WorkoutSpec) commands() ([][]byte, error) {
+	if spec.Type > csafe.WorkoutTypeFixedCalsInterval {
+		return nil, fmt.Errorf("unknown workout type %d", spec.Type)
+	}
+	justRow, splits, interval, variable := workoutTypeTraits(spec.Type)
+
+	switch {
+	case justRow && spec.Duration > 0:
+		return nil, fmt.Errorf("a %s workout has no duration", spec.Type)
+	case !justRow && spec.Duration == 0:
+		return nil, fmt.Errorf("a %s workout needs a duration", spec.Type)
+	case spec.SplitDuration > 0 && !splits:
+		return nil, fmt.Errorf("splits are not valid for a %s workout", spec.Type)
+	case spec.Rest > 0 && !interval:
+		return nil, fmt.Errorf("rest is not valid for a %s workout", spec.Type)
+	case spec.IntervalCount > 0 && !variable:
+		return nil, fmt.Errorf("an interval count is not valid for a %s workout", spec.Type)
+	}
+	if spec.Duration > 0 && ValidateSplitDuration(spec.Type, spec.DurationType) != nil {
+		return nil, fmt.Errorf("%s duration is not valid for a %s workout", spec.DurationType, spec.Type)
+	}
+	if spec.SplitDuration > 0 {
+		if err := ValidateSplitDuration(spec.Type, spec.DurationType); err != nil {
+			return nil, err
+		}
 	}
 
-	if splitDuration > 0 {
-		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetSplitDuration,
-			byte(csafe.DurationTypeTime),
-			byte((splitDuration>>24)&0xFF),
-			byte((splitDuration>>16)&0xFF),
-			byte((splitDuration>>8)&0xFF),
-			byte(splitDuration&0xFF)))
+	var pmCmds [][]byte
+	if variable {
+		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetWorkoutIntervalCount, spec.IntervalCount))
+	}
+	pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(spec.Type)))
+	if variable {
+		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetIntervalType, byte(spec.IntervalType)))
+	}
+	if spec.Duration > 0 {
+		pmCmds = append(pmCmds, durationCommand(csafe.PMCmdSetWorkoutDuration, spec.DurationType, spec.Duration))
 	}
+	if spec.SplitDuration > 0 {
+		pmCmds = append(pmCmds, durationCommand(csafe.PMCmdSetSplitDuration, spec.DurationType, spec.SplitDuration))
+	}
+	if interval {
+		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetRestDuration,
+			byte((spec.Rest>>8)&0xFF),
+			byte(spec.Rest&0xFF)))
+	}
+	if spec.TargetPace > 0 {
+		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetTargetPaceTime,
+			byte((spec.TargetPace>>24)&0xFF),
+			byte((spec.TargetPace>>16)&0xFF),
+			byte((spec.TargetPace>>8)&0xFF),
+			byte(spec.TargetPace&0xFF)))
+	}
+	if !justRow || spec.SplitDuration > 0 || spec.TargetPace > 0 {
+		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdConfigureWorkout, 0x01)) // Enable
+	}
+	pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetScreenState,
+		byte(csafe.ScreenTypeWorkout),
+		byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)))
 
-	pmCmds = append(pmCmds,
-		csafe.BuildCommand(csafe.PMCmdConfigureWorkout, 0x01), // Enable
-		csafe.BuildCommand(csafe.PMCmdSetScreenState,
-			byte(csafe.ScreenTypeWorkout),
-			byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)))
+	return pmCmds, nil
+}
 
-	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...)
-	return err
+// durationCommand builds a workout or split duration command
+func durationCommand(cmd byte, durationType csafe.DurationType, duration uint32) []byte {
+	return csafe.BuildCommand(cmd,
+		byte(durationType),
+		byte((duration>>24)&0xFF),
+		byte((duration>>16)&0xFF),
+		byte((duration>>8)&0xFF),
+		byte(duration&0xFF))
 }
 
-// StartFixedCalorieWorkout starts a fixed calorie workout
-// calories is the goal, splitCalories is per split (0 for no splits)
-func (p *PM5) StartFixedCalorieWorkout(calories uint32, splitCalories uint32) error {
-	workoutType := csafe.WorkoutTypeJustRowNoSplits // Will be updated
-	if splitCalories > 0 {
-		workoutType = csafe.WorkoutTypeFixedCalorieSplits
+// SetWorkoutFromSpec validates spec and programs it in a single frame, leaving
+// the PM ready to row
+// For variable intervals, call once per interval with IntervalCount set.
+func (p *PM5) SetWorkoutFromSpec(spec WorkoutSpec) error {
+	pmCmds, err := spec.commands()
+	if err != nil {
+		return err
 	}
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmds := [][]byte{
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(workoutType)),
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutDuration,
-			byte(csafe.DurationTypeCalories),
-			byte((calories>>24)&0xFF),
-			byte((calories>>16)&0xFF),
-			byte((calories>>8)&0xFF),
-			byte(calories&0xFF)),
+	if _, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...); err != nil {
+		return err
+	}
+	p.setWorkoutType(spec.Type)
+	if _, _, _, variable := workoutTypeTraits(spec.Type); variable {
+		p.intervalTotal = int(spec.IntervalCount) + 1
+	}
+	return nil
+}
+
+// StartJustRowWorkout starts a simple "Just Row" workout with optional splits
+func (p *PM5) StartJustRowWorkout(withSplits bool) error {
+	workoutType := csafe.WorkoutTypeJustRowNoSplits
+	if withSplits {
+		workoutType = csafe.WorkoutTypeJustRowSplits
 	}
+	return p.SetWorkoutFromSpec(WorkoutSpec{Type: workoutType})
+}
 
-	if splitCalories > 0 {
-		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetSplitDuration,
-			byte(csafe.DurationTypeCalories),
-			byte((splitCalories>>24)&0xFF),
-			byte((splitCalories>>16)&0xFF),
-			byte((splitCalories>>8)&0xFF),
-			byte(splitCalories&0xFF)))
+// StartFixedDistanceWorkout starts a fixed distance workout
+// distance is in meters, splitDistance is in meters (0 for no splits)
+func (p *PM5) StartFixedDistanceWorkout(distance uint32, splitDistance uint32) error {
+	workoutType := csafe.WorkoutTypeFixedDistNoSplits
+	if splitDistance > 0 {
+		workoutType = csafe.WorkoutTypeFixedDistSplits
 	}
+	return p.SetWorkoutFromSpec(WorkoutSpec{
+		Type:          workoutType,
+		Duration:      distance,
+		DurationType:  csafe.DurationTypeDistance,
+		SplitDuration: splitDistance,
+	})
+}
 
-	pmCmds = append(pmCmds,
-		csafe.BuildCommand(csafe.PMCmdConfigureWorkout, 0x01),
-		csafe.BuildCommand(csafe.PMCmdSetScreenState,
-			byte(csafe.ScreenTypeWorkout),
-			byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)))
+// StartFixedTimeWorkout starts a fixed time workout
+// duration is in hundredths of seconds, splitDuration is in hundredths of seconds (0 for no splits)
+func (p *PM5) StartFixedTimeWorkout(duration uint32, splitDuration uint32) error {
+	workoutType := csafe.WorkoutTypeFixedTimeNoSplits
+	if splitDuration > 0 {
+		workoutType = csafe.WorkoutTypeFixedTimeSplits
+	}
+	return p.SetWorkoutFromSpec(WorkoutSpec{
+		Type:          workoutType,
+		Duration:      duration,
+		DurationType:  csafe.DurationTypeTime,
+		SplitDuration: splitDuration,
+	})
+}
 
-	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...)
-	return err
+// StartFixedCalorieWorkout starts a fixed calorie workout
+// calories is the goal, splitCalories is per split (0 for the PM default)
+func (p *PM5) StartFixedCalorieWorkout(calories uint32, splitCalories uint32) error {
+	return p.SetWorkoutFromSpec(WorkoutSpec{
+		Type:          csafe.WorkoutTypeFixedCalorieSplits,
+		Duration:      calories,
+		DurationType:  csafe.DurationTypeCalories,
+		SplitDuration: splitCalories,
+	})
 }
 
 // StartFixedDistanceIntervalWorkout starts a fixed distance interval workout
 // distance is in meters, restSeconds is rest duration in seconds
 func (p *PM5) StartFixedDistanceIntervalWorkout(distance uint32, restSeconds uint16) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	pmCmds := [][]byte{
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(csafe.WorkoutTypeFixedDistInterval)),
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutDuration,
-			byte(csafe.DurationTypeDistance),
-			byte((distance>>24)&0xFF),
-			byte((distance>>16)&0xFF),
-			byte((distance>>8)&0xFF),
-			byte(distance&0xFF)),
-		csafe.BuildCommand(csafe.PMCmdSetRestDuration,
-			byte((restSeconds>>8)&0xFF),
-			byte(restSeconds&0xFF)),
-		csafe.BuildCommand(csafe.PMCmdConfigureWorkout, 0x01),
-		csafe.BuildCommand(csafe.PMCmdSetScreenState,
-			byte(csafe.ScreenTypeWorkout),
-			byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)),
-	}
-
-	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...)
-	return err
+	return p.SetWorkoutFromSpec(WorkoutSpec{
+		Type:         csafe.WorkoutTypeFixedDistInterval,
+		Duration:     distance,
+		DurationType: csafe.DurationTypeDistance,
+		Rest:         restSeconds,
+	})
 }
 
 // StartFixedTimeIntervalWorkout starts a fixed time interval workout
 // duration is in hundredths of seconds, restSeconds is rest duration in seconds
 func (p *PM5) StartFixedTimeIntervalWorkout(duration uint32, restSeconds uint16) error {
+	return p.SetWorkoutFromSpec(WorkoutSpec{
+		Type:         csafe.WorkoutTypeFixedTimeInterval,
+		Duration:     duration,
+		DurationType: csafe.DurationTypeTime,
+		Rest:         restSeconds,
+	})
+}
+
+// MaxVariableIntervals is the most intervals a PM5 variable interval workout holds
+const MaxVariableIntervals = 30
+
+// Interval is one work piece of a variable interval workout
+// Duration uses the units of WorkoutSpec for the unit implied by Type.
+type Interval struct {
+	Type     csafe.IntervalType // Time, distance, calorie or watt-minute, optionally with undefined rest
+	Duration uint32
+	Rest     uint16 // Rest in seconds; must be 0 for undefined rest types
+}
+
+// intervalDurationType returns the duration unit of a work interval type and
+// whether its rest is undefined
+func intervalDurationType(t csafe.IntervalType) (durationType csafe.DurationType, undefinedRest, ok bool) {
+	switch t {
+	case csafe.IntervalTypeTime:
+		return csafe.DurationTypeTime, false, true
+	case csafe.IntervalTypeDist:
+		return csafe.DurationTypeDistance, false, true
+	case csafe.IntervalTypeCalorie:
+		return csafe.DurationTypeCalories, false, true
+	case csafe.IntervalTypeWattMinute:
+		return csafe.DurationTypeWattMin, false, true
+	case csafe.IntervalTypeTimeRestUndefined:
+		return csafe.DurationTypeTime, true, true
+	case csafe.IntervalTypeDistanceRestUndefined:
+		return csafe.DurationTypeDistance, true, true
+	case csafe.IntervalTypeCalorieRestUndefined:
+		return csafe.DurationTypeCalories, true, true
+	case csafe.IntervalTypeWattMinuteRestUndefined:
+		return csafe.DurationTypeWattMin, true, true
+	}
+	return 0, false, false
+}
+
+// StartVariableIntervalWorkout programs a variable interval workout, such as a
+// pyramid or ladder, and leaves the PM ready to row
+// Each interval is programmed in turn by interval count, type, duration and
+// rest, then configured. If any interval has undefined rest the workout is a
+// variable undefined rest interval workout.
+func (p *PM5) StartVariableIntervalWorkout(intervals []Interval) error {
+	if len(intervals) == 0 {
+		return fmt.Errorf("a variable interval workout needs at least one interval")
+	}
+	if len(intervals) > MaxVariableIntervals {
+		return fmt.Errorf("%d intervals exceeds the PM limit of %d", len(intervals), MaxVariableIntervals)
+	}
+
+	workoutType := csafe.WorkoutTypeVariableInterval
+	for _, iv := range intervals {
+		if _, undefinedRest, _ := intervalDurationType(iv.Type); undefinedRest {
+			workoutType = csafe.WorkoutTypeVariableUndefinedRestInterval
+		}
+	}
+
+	var cmds []pmBatchCommand
+	add := func(cmd []byte) {
+		cmds = append(cmds, pmBatchCommand{cmd: cmd})
+	}
+	for i, iv := range intervals {
+		durationType, undefinedRest, ok := intervalDurationType(iv.Type)
+		switch {
+		case !ok:
+			return fmt.Errorf("interval %d: %s is not a work interval type", i+1, iv.Type)
+		case iv.Duration == 0:
+			return fmt.Errorf("interval %d: needs a duration", i+1)
+		case undefinedRest && iv.Rest > 0:
+			return fmt.Errorf("interval %d: rest is not valid for %s", i+1, iv.Type)
+		}
+
+		add(csafe.BuildCommand(csafe.PMCmdSetWorkoutIntervalCount, byte(i)))
+		if i == 0 {
+			add(csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(workoutType)))
+		}
+		add(csafe.BuildCommand(csafe.PMCmdSetIntervalType, byte(iv.Type)))
+		add(durationCommand(csafe.PMCmdSetWorkoutDuration, durationType, iv.Duration))
+		add(csafe.BuildCommand(csafe.PMCmdSetRestDuration,
+			byte((iv.Rest>>8)&0xFF),
+			byte(iv.Rest&0xFF)))
+		add(csafe.BuildCommand(csafe.PMCmdConfigureWorkout, 0x01)) // Enable
+	}
+	add(csafe.BuildCommand(csafe.PMCmdSetScreenState,
+		byte(csafe.ScreenTypeWorkout),
+		byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)))
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	pmCmds := [][]byte{
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutType, byte(csafe.WorkoutTypeFixedTimeInterval)),
-		csafe.BuildCommand(csafe.PMCmdSetWorkoutDuration,
-			byte(csafe.DurationTypeTime),
-			byte((duration>>24)&0xFF),
-			byte((duration>>16)&0xFF),
-			byte((duration>>8)&0xFF),
-			byte(duration&0xFF)),
-		csafe.BuildCommand(csafe.PMCmdSetRestDuration,
-			byte((restSeconds>>8)&0xFF),
-			byte(restSeconds&0xFF)),
-		csafe.BuildCommand(csafe.PMCmdConfigureWorkout, 0x01),
-		csafe.BuildCommand(csafe.PMCmdSetScreenState,
-			byte(csafe.ScreenTypeWorkout),
-			byte(csafe.ScreenValueWorkoutPrepareToRowWorkout)),
-	}
-
-	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...)
-	return err
+	// Many intervals do not fit one frame; sendPMBatch splits them in order
+	if _, err := p.sendPMBatch(context.Background(), csafe.CmdSetPMCfg, cmds, nil); err != nil {
+		return err
+	}
+	p.setWorkoutType(workoutType)
+	p.intervalTotal = len(intervals)
+	return nil
 }
 
 // TerminateWorkout terminates the current workout
@@ -0,0 +1,72 @@
+package pm5
+
+import (
+	"testing"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+func TestValidateSplitDuration(t *testing.T) {
+	tests := []struct {
+		workout csafe.WorkoutType
+		split   csafe.DurationType
+		valid   bool
+	}{
+		{csafe.WorkoutTypeFixedDistSplits, csafe.DurationTypeDistance, true},
+		{csafe.WorkoutTypeFixedDistSplits, csafe.DurationTypeTime, false},
+		{csafe.WorkoutTypeFixedTimeSplits, csafe.DurationTypeTime, true},
+		{csafe.WorkoutTypeFixedTimeSplits, csafe.DurationTypeDistance, false},
+		{csafe.WorkoutTypeFixedCalorieSplits, csafe.DurationTypeCalories, true},
+		{csafe.WorkoutTypeFixedCalorieSplits, csafe.DurationTypeWattMin, false},
+		{csafe.WorkoutTypeJustRowSplits, csafe.DurationTypeDistance, true},
+		{csafe.WorkoutTypeJustRowSplits, csafe.DurationTypeCalories, false},
+		{csafe.WorkoutTypeVariableInterval, csafe.DurationTypeCalories, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSplitDuration(tt.workout, tt.split)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateSplitDuration(%s, %s) = %v, want valid %v", tt.workout, tt.split, err, tt.valid)
+		}
+	}
+}
+
+func TestSetSplitDurationChecksWorkoutType(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady)
+	if err := p.SetWorkoutType(csafe.WorkoutTypeFixedDistSplits); err != nil {
+		t.Fatal(err)
+	}
+
+	// A time split on a distance workout is refused before anything is sent
+	sent := len(dev.GetWritten())
+	if err := p.SetSplitDuration(csafe.DurationTypeTime, 6000); err == nil {
+		t.Error("SetSplitDuration(time) on a distance workout succeeded")
+	}
+	if got := len(dev.GetWritten()); got != sent {
+		t.Errorf("mismatched split sent %d frames, want none", got-sent)
+	}
+
+	queueResponse(t, dev, statusReady)
+	if err := p.SetSplitDuration(csafe.DurationTypeDistance, 500); err != nil {
+		t.Errorf("SetSplitDuration(distance) on a distance workout: %v", err)
+	}
+}
+
+func TestWorkoutSpecSplitMismatch(t *testing.T) {
+	valid := WorkoutSpec{
+		Type:          csafe.WorkoutTypeFixedTimeSplits,
+		Duration:      120000,
+		DurationType:  csafe.DurationTypeTime,
+		SplitDuration: 30000,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("time splits on a time workout: %v", err)
+	}
+
+	mismatched := valid
+	mismatched.DurationType = csafe.DurationTypeDistance
+	if err := mismatched.Validate(); err == nil {
+		t.Error("distance splits on a time workout were accepted")
+	}
+}
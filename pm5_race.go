@@ -0,0 +1,248 @@
+package pm5
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+// ============================================================================
+// Race Configuration
+// ============================================================================
+
+// MaxRaceLanes is the highest lane number accepted by ConfigureRace
+const MaxRaceLanes = 32
+
+// RaceConfig describes a race to program into the PM
+// The lane setup command carries only the erg address and lane, so the total
+// number of lanes is not programmed into each erg.
+type RaceConfig struct {
+	Type          csafe.RaceType
+	Lane          byte   // Lane assigned to this erg (1-MaxRaceLanes)
+	ErgAddress    byte   // Physical address of this erg on the race network
+	Duration      uint32 // Meters, 0.01s, or calories depending on Type
+	OperationType csafe.RaceOperationType
+}
+
+// raceDurationType returns the workout duration type for a race type
+func raceDurationType(t csafe.RaceType) (csafe.DurationType, bool) {
+	switch t {
+	case csafe.RaceTypeFixedDistSingleErg, csafe.RaceTypeFixedDistTeamErg,
+		csafe.RaceTypeFixedDistRelaySingleErg, csafe.RaceTypeFixedDistRelayTeamErg:
+		return csafe.DurationTypeDistance, true
+	case csafe.RaceTypeFixedTimeSingleErg, csafe.RaceTypeFixedTimeTeamErg,
+		csafe.RaceTypeFixedTimeRelaySingleErg, csafe.RaceTypeFixedTimeRelayTeamErg:
+		return csafe.DurationTypeTime, true
+	case csafe.RaceTypeFixedCalSingleErg, csafe.RaceTypeFixedCalTeamErg,
+		csafe.RaceTypeFixedCalRelaySingleErg, csafe.RaceTypeFixedCalRelayTeamErg:
+		return csafe.DurationTypeCalories, true
+	}
+	return 0, false
+}
+
+// ConfigureRace programs the PM for a race
+// The race type, lane, duration, and operation type are sent as a single batch
+func (p *PM5) ConfigureRace(cfg RaceConfig) error {
+	if cfg.Lane < 1 || cfg.Lane > MaxRaceLanes {
+		return fmt.Errorf("lane %d out of range 1-%d", cfg.Lane, MaxRaceLanes)
+	}
+
+	pmCmds := [][]byte{
+		csafe.BuildCommand(csafe.PMCmdSetRaceType, byte(cfg.Type)),
+		csafe.BuildCommand(csafe.PMCmdSetRaceLaneSetup, cfg.ErgAddress, cfg.Lane),
+	}
+
+	if cfg.Type != csafe.RaceTypeWorkoutRaceStart {
+		durationType, ok := raceDurationType(cfg.Type)
+		if !ok {
+			return fmt.Errorf("unsupported race type %d", cfg.Type)
+		}
+		if cfg.Duration == 0 {
+			return fmt.Errorf("race duration must be greater than zero")
+		}
+		pmCmds = append(pmCmds, csafe.BuildCommand(csafe.PMCmdSetWorkoutDuration,
+			byte(durationType),
+			byte((cfg.Duration>>24)&0xFF),
+			byte((cfg.Duration>>16)&0xFF),
+			byte((cfg.Duration>>8)&0xFF),
+			byte(cfg.Duration&0xFF)))
+	}
+
+	pmCmds = append(pmCmds,
+		csafe.BuildCommand(csafe.PMCmdSetRaceOperationType, byte(cfg.OperationType)))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := p.sendPMCommand(csafe.CmdSetPMCfg, pmCmds...)
+	return err
+}
+
+// ============================================================================
+// Race Sync Data
+// ============================================================================
+
+// SyncData is the compact race synchronization packet
+// Wire layout (get and set all): Bytes 0-3 distance in 0.1 m, Bytes 4-5 stroke
+// pace in 0.01 s per 500 m, Byte 6 average heart rate in bpm, Bytes 7-10
+// elapsed time in 0.01 s. Multi-byte fields are big-endian.
+type SyncData struct {
+	Distance     float64       // Meters
+	StrokePace   time.Duration // Per 500m
+	AvgHeartRate byte          // Beats per minute
+	Time         time.Duration
+}
+
+// syncDataLength is the size of the packed SyncData payload
+const syncDataLength = 11
+
+// GetSyncDataAll returns the race sync packet in a single command
+func (p *PM5) GetSyncDataAll() (*SyncData, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.sendPMGet(csafe.CmdGetPMData, csafe.PMCmdGetSyncDataAll)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetSyncDataAll && len(pmResp.Data) >= syncDataLength {
+				return decodeSyncData(pmResp.Data), nil
+			}
+		}
+	}
+
+	return nil, ErrInvalidResponse
+}
+
+// decodeSyncData parses a packed SyncData payload
+func decodeSyncData(d []byte) *SyncData {
+	return &SyncData{
+		Distance:     TenthsToMeters(BytesToUint32BE(d[0:4])),
+		StrokePace:   HundredthsToTime(uint32(BytesToUint16BE(d[4:6]))),
+		AvgHeartRate: d[6],
+		Time:         HundredthsToTime(BytesToUint32BE(d[7:11])),
+	}
+}
+
+// Sync data field limits imposed by the packed encoding
+const (
+	MaxSyncDistance   = float64(math.MaxUint32) / 10                          // Meters
+	MaxSyncStrokePace = time.Duration(math.MaxUint16) * 10 * time.Millisecond // Per 500m
+	MaxSyncTime       = time.Duration(math.MaxUint32) * 10 * time.Millisecond
+)
+
+// validateSyncDistance checks a sync distance fits its encoding
+func validateSyncDistance(meters float64) error {
+	if !(meters >= 0 && meters <= MaxSyncDistance) { // Also rejects NaN
+		return fmt.Errorf("sync distance %.1fm out of range 0-%.1f", meters, MaxSyncDistance)
+	}
+	return nil
+}
+
+// validateSyncStrokePace checks a sync stroke pace fits its encoding
+func validateSyncStrokePace(pace time.Duration) error {
+	if pace < 0 || pace > MaxSyncStrokePace {
+		return fmt.Errorf("sync stroke pace %v out of range 0-%v", pace, MaxSyncStrokePace)
+	}
+	return nil
+}
+
+// validateSyncTime checks a sync time fits its encoding
+func validateSyncTime(t time.Duration) error {
+	if t < 0 || t > MaxSyncTime {
+		return fmt.Errorf("sync time %v out of range 0-%v", t, MaxSyncTime)
+	}
+	return nil
+}
+
+// be32 encodes v as big-endian bytes
+func be32(v uint32) []byte {
+	return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// SetSyncDataAll pushes a competitor's race position to the PM in one command,
+// for a host acting as race controller (e.g. a virtual pace boat)
+// Fields are validated against the SyncData encoding before anything is sent.
+func (p *PM5) SetSyncDataAll(d SyncData) error {
+	if err := validateSyncDistance(d.Distance); err != nil {
+		return err
+	}
+	if err := validateSyncStrokePace(d.StrokePace); err != nil {
+		return err
+	}
+	if err := validateSyncTime(d.Time); err != nil {
+		return err
+	}
+
+	pace := TimeToHundredths(d.StrokePace)
+	data := make([]byte, 0, syncDataLength)
+	data = append(data, be32(MetersToTenths(d.Distance))...)
+	data = append(data, byte(pace>>8), byte(pace))
+	data = append(data, d.AvgHeartRate)
+	data = append(data, be32(TimeToHundredths(d.Time))...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetSyncDataAll, data...)
+	_, err := p.sendPMCommand(csafe.CmdSetPMData, pmCmd)
+	return err
+}
+
+// SetSyncDistance sets the sync distance in meters
+func (p *PM5) SetSyncDistance(meters float64) error {
+	if err := validateSyncDistance(meters); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetSyncDistance, be32(MetersToTenths(meters))...)
+	_, err := p.sendPMCommand(csafe.CmdSetPMData, pmCmd)
+	return err
+}
+
+// SetSyncStrokePace sets the sync stroke pace per 500m
+func (p *PM5) SetSyncStrokePace(pace time.Duration) error {
+	if err := validateSyncStrokePace(pace); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	v := TimeToHundredths(pace)
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetSyncStrokePace, byte(v>>8), byte(v))
+	_, err := p.sendPMCommand(csafe.CmdSetPMData, pmCmd)
+	return err
+}
+
+// SetSyncAvgHeartRate sets the sync average heart rate in beats per minute
+func (p *PM5) SetSyncAvgHeartRate(bpm byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetSyncAvgHeartRate, bpm)
+	_, err := p.sendPMCommand(csafe.CmdSetPMData, pmCmd)
+	return err
+}
+
+// SetSyncTime sets the sync elapsed time
+func (p *PM5) SetSyncTime(t time.Duration) error {
+	if err := validateSyncTime(t); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pmCmd := csafe.BuildCommand(csafe.PMCmdSetSyncTime, be32(TimeToHundredths(t))...)
+	_, err := p.sendPMCommand(csafe.CmdSetPMData, pmCmd)
+	return err
+}
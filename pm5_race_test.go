@@ -0,0 +1,53 @@
+package pm5
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+func TestConfigureRaceCommandBytes(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	queueResponse(t, dev, statusReady)
+
+	err := p.ConfigureRace(RaceConfig{
+		Type:          csafe.RaceTypeFixedDistSingleErg,
+		Lane:          3,
+		ErgAddress:    1,
+		Duration:      2000,
+		OperationType: csafe.RaceOperationTypeRaceInit,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := decodeWritten(t, dev)
+	if len(frames) != 1 {
+		t.Fatalf("wrote %d frames, want 1", len(frames))
+	}
+	want := []byte{
+		0x76, 0x11, // SetPMCfg wrapper, 17 bytes
+		0x09, 0x01, 0x00, // Race type: fixed distance, single erg
+		0x0B, 0x02, 0x01, 0x03, // Lane setup: erg address 1, lane 3
+		0x03, 0x05, 0x80, 0x00, 0x00, 0x07, 0xD0, // Duration: 2000 m
+		0x1E, 0x01, 0x06, // Operation type: race init
+	}
+	if got := frames[0].Contents; !bytes.Equal(got, want) {
+		t.Errorf("contents = % X\nwant       % X", got, want)
+	}
+}
+
+func TestConfigureRaceRejectsLane(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	for _, lane := range []byte{0, MaxRaceLanes + 1} {
+		cfg := RaceConfig{Type: csafe.RaceTypeFixedDistSingleErg, Lane: lane, Duration: 2000}
+		if err := p.ConfigureRace(cfg); err == nil {
+			t.Errorf("lane %d accepted", lane)
+		}
+	}
+	if n := len(dev.GetWritten()); n != 0 {
+		t.Errorf("wrote %d frames for invalid configs", n)
+	}
+}
@@ -0,0 +1,463 @@
+package pm5
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+	"github.com/danhigham/pm5/device"
+)
+
+func TestFrameToggleCheck(t *testing.T) {
+	const toggle = 0x80
+
+	t.Run("off by default", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		queueResponse(t, dev, statusReady)
+		queueResponse(t, dev, statusReady)
+		mustStatus(t, p)
+		mustStatus(t, p)
+	})
+
+	t.Run("flipped toggle accepted", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		p.SetFrameToggleCheck(true)
+		queueResponse(t, dev, statusReady|toggle)
+		queueResponse(t, dev, statusReady)
+		queueResponse(t, dev, statusReady|toggle)
+		mustStatus(t, p)
+		mustStatus(t, p)
+		mustStatus(t, p)
+	})
+
+	t.Run("repeated toggle rejected", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		p.SetFrameToggleCheck(true)
+		queueResponse(t, dev, statusReady|toggle)
+		queueResponse(t, dev, statusReady|toggle)
+		queueResponse(t, dev, statusReady)
+		mustStatus(t, p)
+		if _, err := p.GetStatus(); !errors.Is(err, ErrInvalidResponse) {
+			t.Fatalf("repeated toggle: got %v, want ErrInvalidResponse", err)
+		}
+		// The repeat resynchronizes the expected toggle
+		mustStatus(t, p)
+	})
+
+	t.Run("reset on reconnect", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		p.SetFrameToggleCheck(true)
+		queueResponse(t, dev, statusReady|toggle)
+		queueResponse(t, dev, statusReady|toggle)
+		mustStatus(t, p)
+		if err := p.Disconnect(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		mustStatus(t, p)
+	})
+}
+
+func TestSendPMBatchSplitsStuffedCommands(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	const limit = 32
+	p.maxFrameLen = limit
+
+	// Every data byte is a flag value, so each command doubles in size on the wire
+	var cmds []pmBatchCommand
+	var want []byte
+	for range 6 {
+		cmd := csafe.BuildCommand(0x05, 0xF0, 0xF1, 0xF2, 0xF3)
+		cmds = append(cmds, pmBatchCommand{cmd, 0})
+		want = append(want, cmd...)
+	}
+	for range 3 {
+		queueResponse(t, dev, statusReady, pmData(csafe.CmdSetPMCfg, cmdData(0x05)))
+	}
+
+	merged, err := p.sendPMBatch(context.Background(), csafe.CmdSetPMCfg, cmds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 3 {
+		t.Errorf("merged %d responses, want 3", len(merged))
+	}
+
+	written := dev.GetWritten()
+	if len(written) != 3 {
+		t.Fatalf("sent %d frames, want 3", len(written))
+	}
+	var got []byte
+	for i, w := range written {
+		if len(w) > limit {
+			t.Errorf("frame %d is %d bytes on the wire, limit %d", i, len(w), limit)
+		}
+		f, err := csafe.DecodeFrame(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Contents[0] != csafe.CmdSetPMCfg || int(f.Contents[1]) != len(f.Contents)-2 {
+			t.Errorf("frame %d wrapper = % X", i, f.Contents[:2])
+		}
+		got = append(got, f.Contents[2:]...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("commands across frames = % X, want % X", got, want)
+	}
+}
+
+func TestTimeoutErrorCarriesPartialFrame(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	// The response stops after the status byte, with no stop flag
+	partial := []byte{csafe.StandardFrameStartFlag, statusReady, csafe.CmdGetStatus}
+	dev.QueueResponse(partial)
+
+	_, err := p.GetStatus()
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("GetStatus() error = %v, want a TimeoutError", err)
+	}
+	if !bytes.Equal(te.Partial, partial) {
+		t.Errorf("Partial = % X, want % X", te.Partial, partial)
+	}
+	if !errors.Is(err, device.ErrTimeout) {
+		t.Errorf("error %v does not wrap device.ErrTimeout", err)
+	}
+}
+
+func TestTimeoutErrorWithoutResponse(t *testing.T) {
+	p, _, _ := newTestPM5(t)
+
+	_, err := p.GetStatus()
+	var te *TimeoutError
+	if !errors.As(err, &te) {
+		t.Fatalf("GetStatus() error = %v, want a TimeoutError", err)
+	}
+	if len(te.Partial) != 0 {
+		t.Errorf("Partial = % X, want none", te.Partial)
+	}
+}
+
+func TestGetCapabilitiesFallback(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	// A PM that reports a 64-byte receive limit
+	queueResponse(t, dev, statusReady, cmdData(csafe.CmdGetCaps, 64, 120, 50))
+	caps, err := p.GetCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.MaxRxFrame != 64 || p.FrameLimit() != 64 {
+		t.Fatalf("MaxRxFrame = %d, FrameLimit() = %d, want 64", caps.MaxRxFrame, p.FrameLimit())
+	}
+
+	// Older firmware rejects GetCaps
+	queueResponse(t, dev, statusRejected)
+	if _, err := p.GetCapabilities(); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("GetCapabilities() error = %v, want ErrUnsupported", err)
+	}
+	if got := p.FrameLimit(); got != csafe.MaxFrameLength {
+		t.Errorf("FrameLimit() = %d, want MaxFrameLength %d", got, csafe.MaxFrameLength)
+	}
+
+	// An answer without the caps data is treated the same way
+	queueResponse(t, dev, statusReady)
+	if _, err := p.GetCapabilities(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetCapabilities() error = %v, want ErrUnsupported", err)
+	}
+
+	// Commands keep working at the fallback limit
+	queueResponse(t, dev, statusReady, pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetStrokeState, byte(csafe.StrokeStateDriving))))
+	if _, err := p.GetStrokeState(); err != nil {
+		t.Errorf("GetStrokeState() after fallback: %v", err)
+	}
+}
+
+func TestSetProgram(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	queueResponse(t, dev, statusReady)
+	if err := p.SetProgram(csafe.WorkoutNumberDefault3); err != nil {
+		t.Fatal(err)
+	}
+	queueResponse(t, dev, statusReady)
+	if err := p.SetProgramLevel(csafe.WorkoutNumberCustom5, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	frames := decodeWritten(t, dev)
+	wants := [][]byte{
+		{csafe.CmdSetProgram, 0x02, byte(csafe.WorkoutNumberDefault3), 0x00},
+		{csafe.CmdSetProgram, 0x02, byte(csafe.WorkoutNumberCustom5), 0x04},
+	}
+	if len(frames) != len(wants) {
+		t.Fatalf("sent %d frames, want %d", len(frames), len(wants))
+	}
+	for i, want := range wants {
+		if !bytes.Equal(frames[i].Contents, want) {
+			t.Errorf("frame %d = % X, want % X", i, frames[i].Contents, want)
+		}
+	}
+
+	// An out-of-range number is refused before anything is sent
+	if err := p.SetProgram(csafe.WorkoutNumberCustom5 + 1); err == nil {
+		t.Error("SetProgram(out of range) succeeded")
+	}
+	if got := len(dev.GetWritten()); got != len(wants) {
+		t.Errorf("out-of-range program sent a frame")
+	}
+}
+
+func TestSetDistanceGoal(t *testing.T) {
+	tests := []struct {
+		name   string
+		meters uint32
+		want   []byte
+	}{
+		{"marathon in meters", 42195,
+			[]byte{csafe.CmdSetHorizontal, 0x03, 0xD3, 0xA4, csafe.UnitsMeter}},
+		{"largest meter value", 65535,
+			[]byte{csafe.CmdSetHorizontal, 0x03, 0xFF, 0xFF, csafe.UnitsMeter}},
+		{"whole kilometers", 100000,
+			[]byte{csafe.CmdSetHorizontal, 0x03, 0x64, 0x00, csafe.UnitsKm}},
+		{"beyond uint16 meters", 100500,
+			[]byte{csafe.CmdSetPMCfg, 0x07, csafe.PMCmdSetWorkoutDuration, 0x05,
+				byte(csafe.DurationTypeDistance), 0x00, 0x01, 0x88, 0x94}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, dev, _ := newTestPM5(t)
+			queueResponse(t, dev, statusReady)
+			if err := p.SetDistanceGoal(tt.meters); err != nil {
+				t.Fatal(err)
+			}
+			frames := decodeWritten(t, dev)
+			if len(frames) != 1 {
+				t.Fatalf("sent %d frames, want 1", len(frames))
+			}
+			if !bytes.Equal(frames[0].Contents, tt.want) {
+				t.Errorf("SetDistanceGoal(%d) sent % X, want % X", tt.meters, frames[0].Contents, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeartRateValidity(t *testing.T) {
+	tests := []struct {
+		data  []byte
+		want  HeartRateReading
+		shown string
+	}{
+		{[]byte{HeartRateInvalid}, HeartRateReading{BPM: HeartRateInvalid}, "--"},
+		{[]byte{0}, HeartRateReading{}, "--"},
+		{[]byte{142}, HeartRateReading{BPM: 142, Valid: true}, "142"},
+		{[]byte{142, 3}, HeartRateReading{BPM: 142, Valid: true, Zone: 3, HasZone: true}, "142"},
+	}
+
+	for _, tt := range tests {
+		if got := *decodeHeartRate(tt.data); got != tt.want {
+			t.Errorf("decodeHeartRate(% X) = %+v, want %+v", tt.data, got, tt.want)
+		}
+		if got := IsHeartRateValid(tt.data[0]); got != tt.want.Valid {
+			t.Errorf("IsHeartRateValid(%d) = %v, want %v", tt.data[0], got, tt.want.Valid)
+		}
+
+		// The snapshot shows "--" rather than the sentinel
+		s := &WorkoutSnapshot{HeartRate: tt.data[0]}
+		if !strings.Contains(s.String(), "HR: "+tt.shown+" ") {
+			t.Errorf("snapshot with heart rate %d = %q, want HR: %s", tt.data[0], s.String(), tt.shown)
+		}
+	}
+}
+
+func TestRetrySkipsNonIdempotentCommands(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 2}
+
+	t.Run("idempotent getter is retried", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		p.SetRetryPolicy(policy)
+		if _, err := p.GetStatus(); !errors.Is(err, device.ErrTimeout) {
+			t.Fatalf("GetStatus() error = %v, want ErrTimeout", err)
+		}
+		if got := len(dev.GetWritten()); got != 3 {
+			t.Errorf("GetStatus sent %d frames, want 3", got)
+		}
+	})
+
+	t.Run("reset is not retried", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		p.SetRetryPolicy(policy)
+		if err := p.Reset(); !errors.Is(err, device.ErrTimeout) {
+			t.Fatalf("Reset() error = %v, want ErrTimeout", err)
+		}
+		if got := len(dev.GetWritten()); got != 1 {
+			t.Errorf("Reset sent %d frames, want 1", got)
+		}
+	})
+
+	t.Run("reset is retried after opting in", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		p.SetRetryPolicy(policy)
+		p.SetResendNonIdempotent(true)
+		p.Reset()
+		if got := len(dev.GetWritten()); got != 3 {
+			t.Errorf("Reset sent %d frames, want 3", got)
+		}
+	})
+}
+
+func TestRecoverFromBootloader(t *testing.T) {
+	opState := func(s csafe.OperationalState) []byte {
+		return pmData(csafe.CmdGetPMCfg, cmdData(csafe.PMCmdGetOperationalState, byte(s)))
+	}
+
+	for _, s := range []csafe.OperationalState{csafe.OperationalStateInvokeBootloader, csafe.OperationalStateFWUpdate} {
+		if !s.IsInBootloader() {
+			t.Errorf("%s.IsInBootloader() = false", s)
+		}
+	}
+	if csafe.OperationalStateReady.IsInBootloader() {
+		t.Error("OperationalStateReady.IsInBootloader() = true")
+	}
+
+	t.Run("not in bootloader", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		queueResponse(t, dev, statusReady, opState(csafe.OperationalStateReady))
+		if err := p.RecoverFromBootloader(); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(dev.GetWritten()); got != 1 {
+			t.Errorf("sent %d frames, want only the state read", got)
+		}
+	})
+
+	t.Run("reset recovers", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		queueResponse(t, dev, statusReady, opState(csafe.OperationalStateInvokeBootloader))
+		queueResponse(t, dev, statusReady)
+		queueResponse(t, dev, statusReady, opState(csafe.OperationalStateReady))
+		if err := p.RecoverFromBootloader(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("stuck requires reflash", func(t *testing.T) {
+		p, dev, clock := newTestPM5(t)
+		queueResponse(t, dev, statusReady, opState(csafe.OperationalStateFWUpdate))
+		for range bootloaderResetAttempts {
+			queueResponse(t, dev, statusReady)
+			queueResponse(t, dev, statusReady, opState(csafe.OperationalStateFWUpdate))
+		}
+
+		err := p.RecoverFromBootloader()
+		if !errors.Is(err, ErrFirmwareReflashRequired) {
+			t.Fatalf("RecoverFromBootloader() error = %v, want ErrFirmwareReflashRequired", err)
+		}
+
+		var resets int
+		for _, f := range decodeWritten(t, dev) {
+			if bytes.Equal(f.Contents, []byte{csafe.CmdReset}) {
+				resets++
+			}
+		}
+		if resets != bootloaderResetAttempts {
+			t.Errorf("sent %d resets, want %d", resets, bootloaderResetAttempts)
+		}
+		var waited int
+		for _, d := range clock.Slept() {
+			if d == bootloaderResetDelay {
+				waited++
+			}
+		}
+		if waited != bootloaderResetAttempts {
+			t.Errorf("waited for a restart %d times, want %d", waited, bootloaderResetAttempts)
+		}
+	})
+}
+
+func TestSequenceNumbers(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+	start := p.LastSequence()
+
+	var trace bytes.Buffer
+	log.SetOutput(&trace)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	p.SetDebug(true)
+
+	for i := uint64(1); i <= 3; i++ {
+		queueResponse(t, dev, statusReady)
+		if _, err := p.GetStatus(); err != nil {
+			t.Fatal(err)
+		}
+		if got := p.LastSequence(); got != start+i {
+			t.Errorf("LastSequence() after command %d = %d, want %d", i, got, start+i)
+		}
+		// Both the request and the response trace line carry the number
+		if n := strings.Count(trace.String(), fmt.Sprintf(" #%d]", start+i)); n != 2 {
+			t.Errorf("trace has %d lines for exchange %d, want 2:\n%s", n, start+i, trace.String())
+		}
+	}
+
+	// A failed exchange reports its number; each retry is a new exchange
+	p.SetRetryPolicy(RetryPolicy{MaxAttempts: 2})
+	_, err := p.GetStatus()
+	var xe *ExchangeError
+	if !errors.As(err, &xe) {
+		t.Fatalf("GetStatus() error = %v, want an ExchangeError", err)
+	}
+	if want := start + 5; xe.Seq != want || p.LastSequence() != want {
+		t.Errorf("failed exchange Seq = %d, LastSequence() = %d, want %d", xe.Seq, p.LastSequence(), want)
+	}
+	if !errors.Is(err, device.ErrTimeout) {
+		t.Errorf("error %v does not wrap device.ErrTimeout", err)
+	}
+}
+
+func TestTraceNamesPublicMethod(t *testing.T) {
+	p, _, _ := newTestPM5(t)
+
+	var trace bytes.Buffer
+	log.SetOutput(&trace)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	p.SetDebug(true)
+
+	// Each call reaches the device through a different chain of send helpers
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"(*PM5).GetStatus", func() error { _, err := p.GetStatus(); return err }},
+		{"(*PM5).GetPMWorkDistance", func() error { _, err := p.GetPMWorkDistance(); return err }},
+		{"(*PM5).GetIntervalSummary", func() error { _, err := p.GetIntervalSummary(); return err }},
+		{"(*PM5).GetWorkoutSnapshotCtx", func() error { _, err := p.GetWorkoutSnapshot(); return err }},
+	}
+	for _, tt := range tests {
+		trace.Reset()
+		tt.call()
+		var sent int
+		for _, line := range strings.Split(trace.String(), "\n") {
+			if !strings.Contains(line, ">>") {
+				continue
+			}
+			sent++
+			if !strings.Contains(line, "pm5."+tt.name+"\033") {
+				t.Errorf("trace line %q does not name %s", line, tt.name)
+			}
+		}
+		if sent == 0 {
+			t.Errorf("%s wrote no trace lines", tt.name)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package pm5
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+// ============================================================================
+// Stroke Timing Analysis
+// ============================================================================
+
+// StrokeTiming holds the drive/recovery timing of a single stroke
+type StrokeTiming struct {
+	DriveTime    time.Duration
+	RecoveryTime time.Duration
+	Ratio        float64 // Drive time / recovery time (0.5 = classic 1:2 rhythm)
+}
+
+// StrokeTimingSummary contains aggregate timing over all analyzed strokes
+type StrokeTimingSummary struct {
+	Strokes         int
+	AvgDriveTime    time.Duration
+	AvgRecoveryTime time.Duration
+	AvgRatio        float64
+	MinRatio        float64
+	MaxRatio        float64
+}
+
+// StrokeTimingAnalyzer computes drive/recovery ratios from successive StrokeStats
+// Feed it the stats read after each stroke (e.g. on the Recovery stroke state);
+// repeated reads of the same stroke are ignored using the DriveCounter.
+// It is not safe for concurrent use.
+type StrokeTimingAnalyzer struct {
+	window      int
+	recent      []StrokeTiming
+	lastCounter uint16
+	hasLast     bool
+
+	count       int
+	sumDrive    time.Duration
+	sumRecovery time.Duration
+	sumRatio    float64
+	minRatio    float64
+	maxRatio    float64
+}
+
+// NewStrokeTimingAnalyzer creates an analyzer with a rolling average over window strokes
+func NewStrokeTimingAnalyzer(window int) *StrokeTimingAnalyzer {
+	if window < 1 {
+		window = 1
+	}
+	return &StrokeTimingAnalyzer{
+		window: window,
+		recent: make([]StrokeTiming, 0, window),
+	}
+}
+
+// Add records the timing of the stroke described by stats
+// Returns false if the stroke was already recorded or has no usable timing
+func (a *StrokeTimingAnalyzer) Add(stats *StrokeStats) (StrokeTiming, bool) {
+	if stats == nil {
+		return StrokeTiming{}, false
+	}
+	if a.hasLast && stats.DriveCounter == a.lastCounter {
+		return StrokeTiming{}, false
+	}
+	if stats.DriveTime == 0 || stats.RecoveryTime == 0 {
+		return StrokeTiming{}, false
+	}
+
+	a.lastCounter = stats.DriveCounter
+	a.hasLast = true
+
+	timing := StrokeTiming{
+		DriveTime:    stats.DriveDuration(),
+		RecoveryTime: HundredthsToTime(uint32(stats.RecoveryTime)),
+		Ratio:        float64(stats.DriveTime) / float64(stats.RecoveryTime),
+	}
+
+	if len(a.recent) == a.window {
+		copy(a.recent, a.recent[1:])
+		a.recent = a.recent[:a.window-1]
+	}
+	a.recent = append(a.recent, timing)
+
+	if a.count == 0 || timing.Ratio < a.minRatio {
+		a.minRatio = timing.Ratio
+	}
+	if a.count == 0 || timing.Ratio > a.maxRatio {
+		a.maxRatio = timing.Ratio
+	}
+	a.count++
+	a.sumDrive += timing.DriveTime
+	a.sumRecovery += timing.RecoveryTime
+	a.sumRatio += timing.Ratio
+
+	return timing, true
+}
+
+// RollingAverage returns the average timing over the most recent window strokes
+func (a *StrokeTimingAnalyzer) RollingAverage() StrokeTiming {
+	if len(a.recent) == 0 {
+		return StrokeTiming{}
+	}
+
+	var drive, recovery time.Duration
+	var ratio float64
+	for _, t := range a.recent {
+		drive += t.DriveTime
+		recovery += t.RecoveryTime
+		ratio += t.Ratio
+	}
+
+	n := len(a.recent)
+	return StrokeTiming{
+		DriveTime:    drive / time.Duration(n),
+		RecoveryTime: recovery / time.Duration(n),
+		Ratio:        ratio / float64(n),
+	}
+}
+
+// Summary returns aggregate timing over every stroke added so far
+func (a *StrokeTimingAnalyzer) Summary() StrokeTimingSummary {
+	if a.count == 0 {
+		return StrokeTimingSummary{}
+	}
+	return StrokeTimingSummary{
+		Strokes:         a.count,
+		AvgDriveTime:    a.sumDrive / time.Duration(a.count),
+		AvgRecoveryTime: a.sumRecovery / time.Duration(a.count),
+		AvgRatio:        a.sumRatio / float64(a.count),
+		MinRatio:        a.minRatio,
+		MaxRatio:        a.maxRatio,
+	}
+}
+
+// ============================================================================
+// Stroke Counting
+// ============================================================================
+
+// StrokeCounter turns successive DriveCounter readings into a cumulative stroke
+// total that keeps counting past the counter's wrap at 65535
+// The first reading is taken as the strokes already rowed. Counts are derived
+// from the difference between readings, so strokes are not lost between polls
+// as long as fewer than 65536 occur between readings. Call Reset when a new
+// workout restarts the PM's counter. It is not safe for concurrent use.
+type StrokeCounter struct {
+	last    uint16
+	started bool
+	total   uint64
+}
+
+// Add records a DriveCounter reading and returns the cumulative total
+func (c *StrokeCounter) Add(counter uint16) uint64 {
+	if !c.started {
+		c.started = true
+		c.total = uint64(counter)
+	} else {
+		c.total += uint64(counter - c.last) // Wraps correctly in uint16
+	}
+	c.last = counter
+	return c.total
+}
+
+// Total returns the cumulative stroke count
+func (c *StrokeCounter) Total() uint64 {
+	return c.total
+}
+
+// Reset clears the count for a new workout
+func (c *StrokeCounter) Reset() {
+	*c = StrokeCounter{}
+}
+
+// ============================================================================
+// Force Curve Validation
+// ============================================================================
+
+// ValidateForceCurve reports whether the peak of a force curve agrees with the
+// stroke statistics for the same stroke
+// Force plot samples are in lbs while StrokeStats.PeakDriveForce is in 0.1 lbs,
+// so the curve peak is scaled before comparison. tolerancePct is the allowed
+// deviation as a percentage of the stats peak (e.g. 5 for ±5%).
+func ValidateForceCurve(curve []uint16, stats *StrokeStats, tolerancePct float64) bool {
+	if len(curve) == 0 || stats == nil || stats.PeakDriveForce == 0 {
+		return false
+	}
+
+	var peak uint16
+	for _, v := range curve {
+		if v > peak {
+			peak = v
+		}
+	}
+
+	curvePeak := float64(peak) * 10 // lbs -> 0.1 lbs
+	statsPeak := float64(stats.PeakDriveForce)
+	return math.Abs(curvePeak-statsPeak) <= statsPeak*tolerancePct/100
+}
+
+// ============================================================================
+// Force Curve Streaming
+// ============================================================================
+
+// forceCurvePollInterval is how often StreamForceCurves polls the stroke state
+const forceCurvePollInterval = 20 * time.Millisecond
+
+// maxForcePlotBlocks bounds the number of blocks read for a single curve
+const maxForcePlotBlocks = 16
+
+// GetForceCurve reads the complete force curve of the most recent drive
+// Force plot data is returned in blocks of up to 16 points; blocks are read
+// until a short one marks the end of the curve. Call during the Recovery stroke state.
+func (p *PM5) GetForceCurve() ([]uint16, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.readForceCurve(context.Background())
+}
+
+// StreamForceCurves emits the force curve of each completed stroke
+// The stroke state is polled and the curve is read on each transition into
+// Recovery. If the consumer falls behind, older curves are dropped in favour of
+// the newest. Read errors are reported on the error channel without stopping the
+// stream (and dropped if the previous error has not been received), except a
+// disconnect, which is always delivered and ends the stream. Both channels are
+// closed when ctx is done or the stream ends.
+func (p *PM5) StreamForceCurves(ctx context.Context) (<-chan []uint16, <-chan error) {
+	curves := make(chan []uint16, 1)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(curves)
+		defer close(errs)
+
+		ticker := time.NewTicker(forceCurvePollInterval)
+		defer ticker.Stop()
+
+		report := func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+
+		lastState := csafe.StrokeStateRecovery // Don't emit a stale curve on start
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			p.mu.Lock()
+			state, err := p.readStrokeState(ctx)
+			p.mu.Unlock()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if isDisconnect(err) {
+					replaceError(errs, err)
+					return
+				}
+				report(err)
+				continue
+			}
+
+			entered := state == csafe.StrokeStateRecovery && lastState != csafe.StrokeStateRecovery
+			lastState = state
+			if !entered {
+				continue
+			}
+
+			p.mu.Lock()
+			curve, err := p.readForceCurve(ctx)
+			p.mu.Unlock()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if isDisconnect(err) {
+					replaceError(errs, err)
+					return
+				}
+				report(err)
+				continue
+			}
+			if len(curve) == 0 {
+				continue
+			}
+
+			// Coalesce: replace an unread curve with the newer one
+			select {
+			case curves <- curve:
+			default:
+				select {
+				case <-curves:
+				default:
+				}
+				curves <- curve
+			}
+		}
+	}()
+
+	return curves, errs
+}
+
+// readStrokeState reads the stroke state (caller must hold p.mu)
+func (p *PM5) readStrokeState(ctx context.Context) (csafe.StrokeState, error) {
+	contents := csafe.BuildPMCommand(csafe.CmdGetPMData, csafe.BuildCommand(csafe.PMCmdGetStrokeState))
+	resp, err := p.sendCommandCtx(ctx, contents)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cr := range resp.CommandData {
+		if pmResp := cr.FirstPMResponse(); pmResp != nil {
+			if pmResp.Command == csafe.PMCmdGetStrokeState && len(pmResp.Data) >= 1 {
+				return csafe.StrokeState(pmResp.Data[0]), nil
+			}
+		}
+	}
+
+	return 0, ErrInvalidResponse
+}
+
+// readForceCurve reads force plot blocks until the curve ends (caller must hold p.mu)
+func (p *PM5) readForceCurve(ctx context.Context) ([]uint16, error) {
+	var curve []uint16
+	for i := 0; i < maxForcePlotBlocks; i++ {
+		block, err := p.readForcePlotBlock(ctx, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		curve = append(curve, block...)
+		if len(block) < 16 {
+			break
+		}
+	}
+	return curve, nil
+}
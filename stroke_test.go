@@ -0,0 +1,223 @@
+package pm5
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+// strokeStats returns the stats of one stroke with drive and recovery in 0.01s
+func strokeStats(counter uint16, drive byte, recovery uint16) *StrokeStats {
+	return &StrokeStats{DriveCounter: counter, DriveTime: drive, RecoveryTime: recovery}
+}
+
+func TestStrokeTimingAnalyzer(t *testing.T) {
+	a := NewStrokeTimingAnalyzer(2)
+
+	// 0.80s drive / 1.60s recovery is the classic 1:2 rhythm
+	timing, ok := a.Add(strokeStats(1, 80, 160))
+	if !ok {
+		t.Fatal("first stroke not recorded")
+	}
+	want := StrokeTiming{DriveTime: 800 * time.Millisecond, RecoveryTime: 1600 * time.Millisecond, Ratio: 0.5}
+	if timing != want {
+		t.Errorf("Add() = %+v, want %+v", timing, want)
+	}
+
+	// A repeated read of the same stroke and a stroke without timing are skipped
+	if _, ok := a.Add(strokeStats(1, 90, 100)); ok {
+		t.Error("repeated DriveCounter was recorded")
+	}
+	if _, ok := a.Add(strokeStats(2, 0, 100)); ok {
+		t.Error("stroke with no drive time was recorded")
+	}
+	if _, ok := a.Add(nil); ok {
+		t.Error("nil stats were recorded")
+	}
+
+	a.Add(strokeStats(3, 75, 100)) // 0.75
+	a.Add(strokeStats(4, 60, 200)) // 0.30
+
+	// The window holds the last two strokes
+	rolling := a.RollingAverage()
+	if math.Abs(rolling.Ratio-0.525) > 1e-9 {
+		t.Errorf("rolling ratio = %v, want 0.525", rolling.Ratio)
+	}
+	if rolling.DriveTime != 675*time.Millisecond || rolling.RecoveryTime != 1500*time.Millisecond {
+		t.Errorf("rolling times = %v / %v, want 675ms / 1.5s", rolling.DriveTime, rolling.RecoveryTime)
+	}
+
+	summary := a.Summary()
+	if summary.Strokes != 3 {
+		t.Errorf("Strokes = %d, want 3", summary.Strokes)
+	}
+	if math.Abs(summary.AvgRatio-(0.5+0.75+0.3)/3) > 1e-9 {
+		t.Errorf("AvgRatio = %v, want %v", summary.AvgRatio, (0.5+0.75+0.3)/3)
+	}
+	if summary.MinRatio != 0.3 || summary.MaxRatio != 0.75 {
+		t.Errorf("ratio range = %v..%v, want 0.3..0.75", summary.MinRatio, summary.MaxRatio)
+	}
+	if summary.AvgDriveTime != 716666666*time.Nanosecond {
+		t.Errorf("AvgDriveTime = %v, want 716.666666ms", summary.AvgDriveTime)
+	}
+	if summary.AvgRecoveryTime != 1533333333*time.Nanosecond {
+		t.Errorf("AvgRecoveryTime = %v, want 1.533333333s", summary.AvgRecoveryTime)
+	}
+}
+
+func TestStrokeTimingAnalyzerEmpty(t *testing.T) {
+	a := NewStrokeTimingAnalyzer(0)
+	if got := a.RollingAverage(); got != (StrokeTiming{}) {
+		t.Errorf("RollingAverage() = %+v, want zero", got)
+	}
+	if got := a.Summary(); got != (StrokeTimingSummary{}) {
+		t.Errorf("Summary() = %+v, want zero", got)
+	}
+
+	// A window below 1 still keeps the latest stroke
+	a.Add(strokeStats(1, 80, 160))
+	a.Add(strokeStats(2, 50, 100))
+	if got := a.RollingAverage().DriveTime; got != 500*time.Millisecond {
+		t.Errorf("rolling drive time = %v, want 500ms", got)
+	}
+}
+
+func TestStrokeCounter(t *testing.T) {
+	var c StrokeCounter
+
+	// The first reading counts the strokes already rowed; later readings add
+	// the difference, across the wrap from 65535 back to 0
+	readings := []struct {
+		counter uint16
+		total   uint64
+	}{
+		{65530, 65530},
+		{65530, 65530},
+		{65534, 65534},
+		{65535, 65535},
+		{2, 65538},
+		{10, 65546},
+		{65535, 131071},
+		{3, 131075},
+	}
+	for _, r := range readings {
+		if got := c.Add(r.counter); got != r.total {
+			t.Errorf("Add(%d) = %d, want %d", r.counter, got, r.total)
+		}
+	}
+	if got := c.Total(); got != 131075 {
+		t.Errorf("Total() = %d, want 131075", got)
+	}
+
+	c.Reset()
+	if got := c.Add(5); got != 5 {
+		t.Errorf("Add(5) after Reset = %d, want 5", got)
+	}
+}
+
+// forcePlotBlock builds a force plot reply carrying the given words
+func forcePlotBlock(words ...uint16) []byte {
+	data := []byte{byte(len(words) * 2)}
+	for _, w := range words {
+		data = append(data, byte(w>>8), byte(w))
+	}
+	return pmData(csafe.CmdSetUserCfg1, cmdData(csafe.PMCmdGetForcePlotData, data...))
+}
+
+// strokeStateReply builds a stroke state reply
+func strokeStateReply(s csafe.StrokeState) []byte {
+	return pmData(csafe.CmdGetPMData, cmdData(csafe.PMCmdGetStrokeState, byte(s)))
+}
+
+func TestGetForceCurve(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	// A full block of 16 points is followed by a short block ending the curve
+	first := make([]uint16, 16)
+	for i := range first {
+		first[i] = uint16(i * 10)
+	}
+	queueResponse(t, dev, statusReady, forcePlotBlock(first...))
+	queueResponse(t, dev, statusReady, forcePlotBlock(90, 40))
+
+	curve, err := p.GetForceCurve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(first, 90, 40); !slices.Equal(curve, want) {
+		t.Errorf("GetForceCurve() = %v, want %v", curve, want)
+	}
+	if got := len(dev.GetWritten()); got != 2 {
+		t.Errorf("read %d blocks, want 2", got)
+	}
+}
+
+func TestStreamForceCurves(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	// Two strokes: each drive is followed by recovery, when the curve is read
+	queueResponse(t, dev, statusReady, strokeStateReply(csafe.StrokeStateDriving))
+	queueResponse(t, dev, statusReady, strokeStateReply(csafe.StrokeStateRecovery))
+	queueResponse(t, dev, statusReady, forcePlotBlock(50, 180, 60))
+	queueResponse(t, dev, statusReady, strokeStateReply(csafe.StrokeStateRecovery))
+	queueResponse(t, dev, statusReady, strokeStateReply(csafe.StrokeStateDriving))
+	queueResponse(t, dev, statusReady, strokeStateReply(csafe.StrokeStateRecovery))
+	queueResponse(t, dev, statusReady, forcePlotBlock(70, 200, 90))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	curves, errs := p.StreamForceCurves(ctx)
+
+	for _, want := range [][]uint16{{50, 180, 60}, {70, 200, 90}} {
+		select {
+		case curve := <-curves:
+			if !slices.Equal(curve, want) {
+				t.Errorf("curve = %v, want %v", curve, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no curve received, want %v", want)
+		}
+	}
+
+	// Only one curve per stroke: staying in recovery does not read it again
+	cancel()
+	for range curves {
+		t.Error("extra curve received")
+	}
+	for range errs {
+	}
+}
+
+func TestValidateForceCurve(t *testing.T) {
+	// Curve samples are lbs; the stats peak is 0.1 lbs
+	curve := []uint16{0, 40, 95, 150, 182, 160, 110, 60, 10}
+	stats := &StrokeStats{PeakDriveForce: 1800}
+
+	tests := []struct {
+		name      string
+		curve     []uint16
+		stats     *StrokeStats
+		tolerance float64
+		want      bool
+	}{
+		{"peak within tolerance", curve, stats, 5, true},
+		{"exact peak", curve, &StrokeStats{PeakDriveForce: 1820}, 0, true},
+		{"peak outside tolerance", curve, &StrokeStats{PeakDriveForce: 1500}, 5, false},
+		{"curve in stats units", []uint16{400, 1800, 900}, stats, 5, false},
+		{"empty curve", nil, stats, 5, false},
+		{"no stats", curve, nil, 5, false},
+		{"zero stats peak", curve, &StrokeStats{}, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateForceCurve(tt.curve, tt.stats, tt.tolerance); got != tt.want {
+				t.Errorf("ValidateForceCurve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
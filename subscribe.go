@@ -0,0 +1,245 @@
+package pm5
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Streaming Metrics
+// ============================================================================
+
+// Subscribe polls GetWorkoutSnapshot every interval and delivers the results on
+// the returned channel, starting immediately
+// Polls never overlap: if one runs longer than interval, the missed ticks are
+// dropped. A poll that fails is skipped rather than ending the stream, unless
+// the PM has disconnected, in which case the channel is closed. Call the
+// returned stop func to end polling; it closes the channel before returning.
+func (p *PM5) Subscribe(interval time.Duration) (<-chan *WorkoutSnapshot, func(), error) {
+	if interval <= 0 {
+		return nil, nil, fmt.Errorf("subscribe interval must be positive, got %v", interval)
+	}
+	if !p.IsConnected() {
+		return nil, nil, ErrNotConnected
+	}
+
+	ch := make(chan *WorkoutSnapshot, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			snapshot, err := p.GetWorkoutSnapshotCtx(ctx)
+			if err == nil {
+				select {
+				case ch <- snapshot:
+				case <-ctx.Done():
+					return
+				}
+			} else if isDisconnect(err) {
+				return
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(cancel)
+		<-done
+	}
+	return ch, stop, nil
+}
+
+// StreamSnapshots polls GetWorkoutSnapshot every interval until ctx is done
+// Like Subscribe, polls never overlap and the newest snapshot replaces an unread
+// one. Read errors are reported on the error channel without stopping the
+// stream (and dropped if the previous error has not been received), except a
+// disconnect: it is always delivered, and the stream then stops. Enable
+// SetAutoReconnect to have the PM5 try to recover before a disconnect is reported.
+// Both channels are closed when the stream stops.
+func (p *PM5) StreamSnapshots(ctx context.Context, interval time.Duration) (<-chan *WorkoutSnapshot, <-chan error) {
+	snapshots := make(chan *WorkoutSnapshot, 1)
+	errs := make(chan error, 1)
+
+	if interval <= 0 {
+		errs <- fmt.Errorf("stream interval must be positive, got %v", interval)
+		close(snapshots)
+		close(errs)
+		return snapshots, errs
+	}
+
+	go func() {
+		defer close(snapshots)
+		defer close(errs)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			snapshot, err := p.GetWorkoutSnapshotCtx(ctx)
+			switch {
+			case err == nil:
+				// Coalesce: replace an unread snapshot with the newer one
+				select {
+				case snapshots <- snapshot:
+				default:
+					select {
+					case <-snapshots:
+					default:
+					}
+					snapshots <- snapshot
+				}
+			case ctx.Err() != nil:
+				return
+			case isDisconnect(err):
+				replaceError(errs, err)
+				return
+			default:
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return snapshots, errs
+}
+
+// replaceError delivers err on a buffered error channel, discarding an unread
+// error if the buffer is full (the caller must be the only sender)
+func replaceError(errs chan error, err error) {
+	select {
+	case errs <- err:
+	default:
+		select {
+		case <-errs:
+		default:
+		}
+		errs <- err
+	}
+}
+
+// ============================================================================
+// Health Monitoring
+// ============================================================================
+
+// HealthMonitor polls the PM's status type and value in the background and
+// caches the result, so a dashboard can check each erg's health without
+// sending commands itself
+type HealthMonitor struct {
+	mu     sync.Mutex
+	status *HealthStatus // nil while unknown, including when unsupported
+	stop   func()
+}
+
+// MonitorHealth reads the health status now and then every interval until the
+// monitor is stopped
+// If the PM does not support the status commands, polling ends and the monitor
+// reports healthy with an unknown status. A failed poll keeps the last status;
+// a disconnect ends polling.
+func (p *PM5) MonitorHealth(interval time.Duration) (*HealthMonitor, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("health poll interval must be positive, got %v", interval)
+	}
+	if !p.IsConnected() {
+		return nil, ErrNotConnected
+	}
+
+	m := &HealthMonitor{stop: func() {}}
+	if !m.poll(p) {
+		return m, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			if !m.poll(p) {
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	m.stop = func() {
+		once.Do(cancel)
+		<-done
+	}
+	return m, nil
+}
+
+// poll reads the health status once and reports whether polling should go on
+func (m *HealthMonitor) poll(p *PM5) bool {
+	status, err := p.GetHealthStatus()
+	switch {
+	case err == nil:
+		m.mu.Lock()
+		m.status = status
+		m.mu.Unlock()
+		return true
+	case errors.Is(err, ErrUnsupported):
+		m.mu.Lock()
+		m.status = nil
+		m.mu.Unlock()
+		return false
+	default:
+		return !isDisconnect(err)
+	}
+}
+
+// Healthy reports whether the last poll found no fault
+// An unknown status, e.g. on firmware without the status commands, counts as
+// healthy.
+func (m *HealthMonitor) Healthy() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status == nil || m.status.Healthy()
+}
+
+// Status returns the last polled health status, or nil if it is unknown
+func (m *HealthMonitor) Status() *HealthStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status == nil {
+		return nil
+	}
+	status := *m.status
+	return &status
+}
+
+// Stop ends polling and waits for a poll in progress to finish
+func (m *HealthMonitor) Stop() {
+	m.stop()
+}
@@ -0,0 +1,140 @@
+package pm5
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+	"github.com/danhigham/pm5/device"
+)
+
+// statusRejected is a response status byte: state machine Ready, previous frame rejected
+const statusRejected = statusReady | csafe.PrevFrameStatusReject
+
+func healthResponse(statusType csafe.StatusType, value uint16) []byte {
+	return pmData(csafe.CmdGetPMData,
+		cmdData(csafe.PMCmdGetStatusType, byte(statusType)),
+		cmdData(csafe.PMCmdGetStatusValue, byte(value>>8), byte(value)))
+}
+
+func TestMonitorHealth(t *testing.T) {
+	t.Run("fault is unhealthy", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		queueResponse(t, dev, statusReady, healthResponse(csafe.StatusTypeFault, 42))
+
+		m, err := p.MonitorHealth(time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Stop()
+
+		if m.Healthy() {
+			t.Error("Healthy() = true with a fault reported")
+		}
+		if s := m.Status(); s == nil || s.Type != csafe.StatusTypeFault || s.Value != 42 {
+			t.Errorf("Status() = %+v, want fault 42", s)
+		}
+	})
+
+	t.Run("no fault is healthy", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		queueResponse(t, dev, statusReady, healthResponse(csafe.StatusTypeNone, 0))
+
+		m, err := p.MonitorHealth(time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Stop()
+
+		if !m.Healthy() {
+			t.Error("Healthy() = false with no fault reported")
+		}
+	})
+
+	t.Run("unsupported is unknown and healthy", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		queueResponse(t, dev, statusRejected)
+
+		m, err := p.MonitorHealth(time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Stop()
+
+		if !m.Healthy() {
+			t.Error("Healthy() = false when the status commands are unsupported")
+		}
+		if s := m.Status(); s != nil {
+			t.Errorf("Status() = %+v, want nil", s)
+		}
+	})
+
+	t.Run("polls update the cache", func(t *testing.T) {
+		p, dev, _ := newTestPM5(t)
+		queueResponse(t, dev, statusReady, healthResponse(csafe.StatusTypeNone, 0))
+		queueResponse(t, dev, statusReady, healthResponse(csafe.StatusTypeFault, 7))
+
+		m, err := p.MonitorHealth(time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Stop()
+
+		deadline := time.Now().Add(time.Second)
+		for m.Healthy() {
+			if time.Now().After(deadline) {
+				t.Fatal("fault from a later poll never reached the cache")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+func TestStreamSnapshotsStopsOnDisconnect(t *testing.T) {
+	p, dev, _ := newTestPM5(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	snapshots, errs := p.StreamSnapshots(ctx, time.Millisecond)
+
+	// Nothing is queued, so polls time out: reported, but the stream keeps going
+	select {
+	case err := <-errs:
+		if isDisconnect(err) {
+			t.Fatalf("first error = %v, want a timeout", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no error reported for a timed out poll")
+	}
+
+	// The device goes away: the disconnect is reported and the stream stops
+	// without waiting for ctx
+	dev.Close()
+	deadline := time.After(time.Second)
+	var last error
+	for errs != nil {
+		select {
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			last = err
+		case <-deadline:
+			t.Fatal("stream still running after the device closed")
+		}
+	}
+	if !errors.Is(last, device.ErrDeviceNotOpen) {
+		t.Errorf("last error = %v, want ErrDeviceNotOpen", last)
+	}
+	select {
+	case _, ok := <-snapshots:
+		if ok {
+			t.Error("snapshot delivered after the disconnect")
+		}
+	case <-time.After(time.Second):
+		t.Error("snapshot channel not closed")
+	}
+}
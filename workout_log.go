@@ -0,0 +1,128 @@
+package pm5
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/danhigham/pm5/csafe"
+)
+
+// ============================================================================
+// Internal Workout Log
+// ============================================================================
+
+// LogStructure describes the PM's internal log of completed workouts
+// Wire layout: GetInternalLogParams Bytes 0-3 address of the first entry and
+// Bytes 4-5 entry count; GetCurrentLogStructure Byte 0 structure version and
+// Bytes 1-2 entry size in bytes. All values are big-endian.
+type LogStructure struct {
+	StartAddress uint32
+	EntryCount   uint16
+	EntrySize    uint16
+	Version      byte
+}
+
+// LogEntry is the summary of one logged workout
+// Wire layout at the start of each entry: Bytes 0-1 date (year since 2000 in
+// the top 7 bits, month in the next 4, day in the low 5), Byte 2 hour, Byte 3
+// minute, Byte 4 workout type, Bytes 5-8 work time in 0.01s, Bytes 9-12
+// distance in tenths of meters, all big-endian.
+type LogEntry struct {
+	Date        time.Time // PM local time
+	WorkoutType csafe.WorkoutType
+	Time        time.Duration
+	Distance    float64       // Meters
+	AvgPace     time.Duration // Per 500m, derived from time and distance
+}
+
+// logSummaryLength is the number of bytes of each entry decoded into a LogEntry
+const logSummaryLength = 13
+
+// GetLogStructure returns the layout of the internal workout log, reading the
+// log parameters and structure in a single frame
+func (p *PM5) GetLogStructure() (*LogStructure, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.logStructure()
+}
+
+// logStructure is GetLogStructure for callers already holding p.mu
+func (p *PM5) logStructure() (*LogStructure, error) {
+	resp, err := p.sendPMCommand(csafe.CmdGetPMCfg,
+		csafe.BuildCommand(csafe.PMCmdGetInternalLogParams),
+		csafe.BuildCommand(csafe.PMCmdGetCurrentLogStructure))
+	if err != nil {
+		return nil, err
+	}
+
+	ls := &LogStructure{}
+	found := 0
+	for _, cr := range resp.CommandData {
+		for _, pmResp := range cr.PMResponses {
+			switch pmResp.Command {
+			case csafe.PMCmdGetInternalLogParams:
+				if len(pmResp.Data) >= 6 {
+					ls.StartAddress = BytesToUint32BE(pmResp.Data[0:4])
+					ls.EntryCount = BytesToUint16BE(pmResp.Data[4:6])
+					found++
+				}
+			case csafe.PMCmdGetCurrentLogStructure:
+				if len(pmResp.Data) >= 3 {
+					ls.Version = pmResp.Data[0]
+					ls.EntrySize = BytesToUint16BE(pmResp.Data[1:3])
+					found++
+				}
+			}
+		}
+	}
+
+	if found < 2 {
+		return nil, ErrInvalidResponse
+	}
+	if ls.EntryCount > 0 && ls.EntrySize < logSummaryLength {
+		return nil, fmt.Errorf("%w: log entry size %d is shorter than the summary", ErrInvalidResponse, ls.EntrySize)
+	}
+	return ls, nil
+}
+
+// GetLogEntry reads the summary of a logged workout
+// Entries are in the order they were logged, so the most recent session is
+// EntryCount-1 from GetLogStructure.
+func (p *PM5) GetLogEntry(index int) (*LogEntry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ls, err := p.logStructure()
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= int(ls.EntryCount) {
+		return nil, fmt.Errorf("log entry %d out of range (%d entries)", index, ls.EntryCount)
+	}
+
+	address := ls.StartAddress + uint32(index)*uint32(ls.EntrySize)
+	data, err := p.readMemoryBlock(csafe.PMCmdGetInternalLogMemory, address, logSummaryLength)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < logSummaryLength {
+		return nil, ErrInvalidResponse
+	}
+	return decodeLogEntry(data), nil
+}
+
+// decodeLogEntry parses the summary at the start of a log entry
+func decodeLogEntry(d []byte) *LogEntry {
+	date := BytesToUint16BE(d[0:2])
+	entry := &LogEntry{
+		Date: time.Date(2000+int(date>>9), time.Month(date>>5&0x0F), int(date&0x1F),
+			int(d[2]), int(d[3]), 0, 0, time.Local),
+		WorkoutType: csafe.WorkoutType(d[4]),
+		Time:        HundredthsToTime(BytesToUint32BE(d[5:9])),
+		Distance:    TenthsToMeters(BytesToUint32BE(d[9:13])),
+	}
+	if entry.Distance > 0 {
+		entry.AvgPace = time.Duration(float64(entry.Time) * 500 / entry.Distance)
+	}
+	return entry
+}